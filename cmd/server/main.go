@@ -19,6 +19,9 @@ func main() {
 
 	var c config.Config
 	conf.MustLoad(*configFile, &c)
+	// Injected 5xx responses would trip the breaker, which then answers with
+	// its own 503s and makes failure sequences unpredictable.
+	c.Middlewares.Breaker = false
 
 	server := rest.MustNewServer(c.RestConf)
 	defer server.Stop()
@@ -28,6 +31,9 @@ func main() {
 	scenarioHandler := handler.NewScenarioHandler(svcCtx)
 	healthHandler := handler.NewHealthHandler(svcCtx)
 	testHandler := handler.NewTestHandler(svcCtx)
+	sessionHandler := handler.NewSessionHandler(svcCtx)
+	eventsHandler := handler.NewEventsHandler(svcCtx)
+	systemHandler := handler.NewSystemHandler(svcCtx)
 
 	server.AddRoute(rest.Route{
 		Method:  http.MethodPost,
@@ -66,6 +72,68 @@ func main() {
 		Handler: scenarioHandler.GetCurrentSession,
 	})
 
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/sessions",
+		Handler: sessionHandler.ListSessions,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/sessions",
+		Handler: scenarioHandler.StartCompositeScenario,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/sessions/history",
+		Handler: sessionHandler.SessionHistory,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/sessions/:id",
+		Handler: sessionHandler.GetSession,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/sessions/:id/report",
+		Handler: sessionHandler.SessionReport,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/sessions/:id/labels",
+		Handler: sessionHandler.SetSessionLabels,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/sessions/:id/answer-key",
+		Handler: sessionHandler.AnswerKey,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/sessions/:id/stop",
+		Handler: sessionHandler.StopSession,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodPost,
+		Path:    "/api/v1/sessions/:id/extend",
+		Handler: sessionHandler.ExtendSession,
+	})
+
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/events",
+		Handler: eventsHandler.Stream,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/system",
+		Handler: systemHandler.GetSystem,
+	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/api/v1/system/last-exit",
+		Handler: systemHandler.LastExit,
+	})
+
 	server.AddRoute(rest.Route{
 		Method:  http.MethodGet,
 		Path:    "/health",
@@ -76,6 +144,11 @@ func main() {
 		Path:    "/ready",
 		Handler: healthHandler.ReadyCheck,
 	})
+	server.AddRoute(rest.Route{
+		Method:  http.MethodGet,
+		Path:    "/startup",
+		Handler: healthHandler.StartupCheck,
+	})
 	server.AddRoute(rest.Route{
 		Method:  http.MethodGet,
 		Path:    "/api/v1/mock-service",
@@ -94,7 +167,12 @@ func main() {
 	})
 
 	server.Use(handler.LatencyMiddleware(svcCtx))
+	server.Use(handler.ErrorMiddleware(svcCtx))
+	server.Use(handler.ConnectionFaultMiddleware(svcCtx))
+
+	svcCtx.Lifecycle.Listen()
+	svcCtx.Lifecycle.Startup(c.Startup, c.System.StateDir)
 
 	fmt.Printf("Starting MockServer at %s:%d\n", c.Host, c.Port)
-	server.Start()
+	server.StartWithOpts(svcCtx.Lifecycle.StartOption(), eventsHandler.StartOption("/api/v1/events"))
 }
@@ -4,4 +4,67 @@ import "github.com/zeromicro/go-zero/rest"
 
 type Config struct {
 	rest.RestConf
+	History  HistoryConf
+	System   SystemConf
+	Startup  StartupConf
+	Health   HealthConf
+	Webhooks []WebhookConf `json:",optional"`
+}
+
+type SystemConf struct {
+	// CgroupRoot is where cgroup limits are read from, fake files can be used
+	// to simulate a container.
+	CgroupRoot string `json:",default=/sys/fs/cgroup"`
+	// StateDir keeps files that must survive a restart of the process, such
+	// as exit markers. Mount a volume here when running in a container.
+	StateDir string `json:",default=/tmp/mockserver"`
+}
+
+// StartupConf injects faults into the startup phase. Every field can also be
+// set with its environment variable, which takes precedence over the file.
+type StartupConf struct {
+	// BindDelay delays binding the port by this many seconds.
+	BindDelay int `json:",default=0,env=MOCKSERVER_BIND_DELAY"`
+	// NotReadyFor makes /ready fail for this many seconds after boot.
+	NotReadyFor int `json:",default=0,env=MOCKSERVER_NOT_READY_FOR"`
+	// Fail exits at startup with a configuration error.
+	Fail         bool `json:",default=false,env=MOCKSERVER_FAIL_STARTUP"`
+	FailExitCode int  `json:",default=1,env=MOCKSERVER_FAIL_EXIT_CODE"`
+	// DegradeAfter starts the health_check scenario this many seconds after
+	// boot, so the service starts healthy and then degrades.
+	DegradeAfter int `json:",default=0,env=MOCKSERVER_DEGRADE_AFTER"`
+}
+
+// HealthConf holds the rules that turn component checks into the overall
+// health status.
+type HealthConf struct {
+	// Components overrides the default db, cache, downstream and disk checks.
+	Components []ComponentConf `json:",optional"`
+	// DegradedStatusCode is returned when only non-critical components fail.
+	DegradedStatusCode int `json:",default=200"`
+}
+
+type ComponentConf struct {
+	Name string
+	// Critical components make the service unhealthy when they are down,
+	// the others only degrade it.
+	Critical  bool    `json:",default=false"`
+	LatencyMs float64 `json:",default=1"`
+}
+
+type HistoryConf struct {
+	MaxSessions    int    `json:",default=100"`
+	SampleInterval int    `json:",default=5"`
+	MaxSamples     int    `json:",default=720"`
+	File           string `json:",optional"`
+}
+
+type WebhookConf struct {
+	Url    string
+	Secret string `json:",optional"`
+	// Events limits the event types sent to this target, all when empty.
+	Events        []string `json:",optional"`
+	Timeout       int      `json:",default=5"`
+	MaxRetries    int      `json:",default=3"`
+	RetryInterval int      `json:",default=500"`
 }
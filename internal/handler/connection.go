@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// breakConnection takes the connection over from net/http and breaks it the
+// way fault says. Connections that cannot be hijacked, such as HTTP/2
+// streams, get a plain 502 instead.
+func breakConnection(w http.ResponseWriter, r *http.Request, fault *scenarios.ConnectionFault) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		httpx.ErrorCtx(r.Context(), w, fmt.Errorf("connection fault %s: %w", fault.Mode, http.ErrNotSupported))
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		httpx.ErrorCtx(r.Context(), w, fmt.Errorf("connection fault %s: %w", fault.Mode, err))
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Time{})
+
+	logx.WithContext(r.Context()).Infof("connection fault %s injected for %s %s", fault.Mode, r.Method, r.URL.Path)
+
+	switch fault.Mode {
+	case scenarios.ConnReset:
+		// With a zero linger, Close sends RST instead of FIN.
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetLinger(0)
+		}
+	case scenarios.ConnCloseHeaders:
+		// The Content-Length makes lenient clients, which take EOF as the end
+		// of the headers, fail as well.
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nContent-Ty", fault.BodyBytes)
+		buf.Flush()
+	case scenarios.ConnTruncate:
+		writeHead(buf, fault.BodyBytes)
+		buf.Write(bytes.Repeat([]byte("x"), int(float64(fault.BodyBytes)*fault.TruncatePercent/100)))
+		buf.Flush()
+	case scenarios.ConnHang:
+		writeHead(buf, fault.BodyBytes)
+		buf.Flush()
+		hang(r, conn, buf, fault)
+	}
+}
+
+func writeHead(buf *bufio.ReadWriter, contentLength int) {
+	fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Length: %d\r\nDate: %s\r\n\r\n",
+		contentLength, time.Now().UTC().Format(http.TimeFormat))
+}
+
+// hang waits for the configured time, the client closing its side, the
+// server timeout or the scenario stopping, whichever comes first.
+func hang(r *http.Request, conn net.Conn, buf *bufio.ReadWriter, fault *scenarios.ConnectionFault) {
+	// net/http no longer watches a hijacked connection, so reading it is the
+	// only way to notice the client going away. Reading conn rather than buf
+	// keeps net/http from cancelling the request context when it closes.
+	buf.Discard(buf.Reader.Buffered())
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	var timeout <-chan time.Time
+	if fault.Hang > 0 {
+		timer := time.NewTimer(fault.Hang)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-timeout:
+	case <-closed:
+	case <-r.Context().Done():
+	case <-fault.Stopped:
+	}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/metrics"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// sleep waits for d unless the request ends first, because the client went
+// away or go-zero's Timeout fired. It reports whether the whole delay
+// elapsed; when it did not, nothing must be written to w.
+func sleep(r *http.Request, d time.Duration, source string) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		reason := "client_cancelled"
+		if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			reason = "server_timeout"
+		}
+		metrics.DelaysAbandoned.Inc(source, reason)
+		logx.WithContext(r.Context()).Infof("%s delay of %v abandoned: %s", source, d, reason)
+		return false
+	}
+}
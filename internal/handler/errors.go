@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Z3Labs/MockServer/internal/manager"
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// writeError maps manager errors to status codes with a JSON body and falls
+// back to the go-zero default for everything else.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *scenarios.ValidationError
+	var conflictErr *manager.ConflictError
+
+	switch {
+	case errors.As(err, &validationErr):
+		httpx.WriteJsonCtx(r.Context(), w, http.StatusBadRequest, map[string]interface{}{
+			"error":  "invalid scenario parameters",
+			"fields": validationErr.Fields,
+		})
+	case errors.As(err, &conflictErr):
+		httpx.WriteJsonCtx(r.Context(), w, http.StatusConflict, map[string]interface{}{
+			"error":     conflictErr.Error(),
+			"conflicts": conflictErr.Conflicts,
+		})
+	case errors.Is(err, manager.ErrSessionNotFound):
+		httpx.WriteJsonCtx(r.Context(), w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	default:
+		httpx.ErrorCtx(r.Context(), w, err)
+	}
+}
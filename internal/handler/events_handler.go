@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/manager"
+	"github.com/Z3Labs/MockServer/internal/svc"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const (
+	keepAliveInterval = 15 * time.Second
+	reconnectDelayMs  = 1000
+)
+
+type EventsHandler struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewEventsHandler(svcCtx *svc.ServiceContext) *EventsHandler {
+	return &EventsHandler{
+		svcCtx: svcCtx,
+	}
+}
+
+// StartOption keeps the server timeouts from cutting the stream served at
+// path. go-zero's timeout middleware leaves event-stream requests alone, and
+// the connection deadlines can only be lifted before go-zero wraps the
+// ResponseWriter, so both happen ahead of its router.
+func (h *EventsHandler) StartOption(path string) rest.StartOption {
+	return func(svr *http.Server) {
+		next := svr.Handler
+		svr.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == path {
+				r.Header.Set("Accept", "text/event-stream")
+				rc := http.NewResponseController(w)
+				rc.SetReadDeadline(time.Time{})
+				rc.SetWriteDeadline(time.Time{})
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Stream sends scenario lifecycle events as Server-Sent Events. Clients that
+// reconnect with Last-Event-ID receive the events they missed.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Types     string `form:"types,optional"`
+		SessionId string `form:"session_id,optional"`
+	}
+	if err := httpx.ParseForm(r, &req); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpx.ErrorCtx(r.Context(), w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	var types map[string]bool
+	if req.Types != "" {
+		types = make(map[string]bool)
+		for _, t := range strings.Split(req.Types, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+	lastId, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	missed, events, unsubscribe := h.svcCtx.ScenarioManager.Events().Subscribe(lastId)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelayMs)
+
+	send := func(event manager.Event) error {
+		if types != nil && !types[event.Type] {
+			return nil
+		}
+		if req.SessionId != "" && event.SessionId != req.SessionId {
+			return nil
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+		return err
+	}
+
+	for _, event := range missed {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := send(event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
-	"time"
+	"strconv"
 
+	"github.com/Z3Labs/MockServer/internal/health"
+	"github.com/Z3Labs/MockServer/internal/metrics"
 	"github.com/Z3Labs/MockServer/internal/scenarios"
 	"github.com/Z3Labs/MockServer/internal/svc"
 	"github.com/zeromicro/go-zero/rest/httpx"
@@ -19,44 +21,77 @@ func NewHealthHandler(svcCtx *svc.ServiceContext) *HealthHandler {
 	}
 }
 
+// probeScenarios lists, per probe, the scenarios that can fail it in the
+// order they are consulted.
+var probeScenarios = map[string][]string{
+	scenarios.ProbeLiveness:  {"liveness_probe", "health_check"},
+	scenarios.ProbeReadiness: {"readiness_probe", "health_check"},
+	scenarios.ProbeStartup:   {"startup_probe"},
+}
+
 func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	scenario, ok := h.svcCtx.ScenarioManager.GetScenario("health_check")
-	if !ok {
-		h.writeHealthy(w, r)
-		return
-	}
+	h.probe(w, r, scenarios.ProbeLiveness)
+}
 
-	healthScenario, ok := scenario.(*scenarios.HealthCheckFailure)
-	if !ok {
-		h.writeHealthy(w, r)
+func (h *HealthHandler) ReadyCheck(w http.ResponseWriter, r *http.Request) {
+	if h.svcCtx.Lifecycle.Starting() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		httpx.OkJsonCtx(r.Context(), w, map[string]string{
+			"status": "starting",
+		})
 		return
 	}
-
-	shouldFail, statusCode, delay := healthScenario.ShouldFail()
-
-	if delay > 0 {
-		time.Sleep(delay)
-	}
-
-	if shouldFail {
-		w.WriteHeader(statusCode)
+	if !h.svcCtx.Lifecycle.Ready() {
+		w.WriteHeader(http.StatusServiceUnavailable)
 		httpx.OkJsonCtx(r.Context(), w, map[string]string{
-			"status": "unhealthy",
+			"status": "shutting_down",
 		})
 		return
 	}
 
-	h.writeHealthy(w, r)
+	h.probe(w, r, scenarios.ProbeReadiness)
 }
 
-func (h *HealthHandler) ReadyCheck(w http.ResponseWriter, r *http.Request) {
-	h.HealthCheck(w, r)
+func (h *HealthHandler) StartupCheck(w http.ResponseWriter, r *http.Request) {
+	h.probe(w, r, scenarios.ProbeStartup)
 }
 
-func (h *HealthHandler) writeHealthy(w http.ResponseWriter, r *http.Request) {
-	httpx.OkJsonCtx(r.Context(), w, map[string]string{
-		"status": "healthy",
-	})
+func (h *HealthHandler) probe(w http.ResponseWriter, r *http.Request, probe string) {
+	for _, name := range probeScenarios[probe] {
+		scenario, ok := h.svcCtx.ScenarioManager.GetScenario(name)
+		if !ok {
+			continue
+		}
+
+		healthScenario, ok := scenario.(*scenarios.HealthCheckFailure)
+		if !ok {
+			continue
+		}
+
+		shouldFail, statusCode, delay := healthScenario.ShouldFail(probe)
+
+		if delay > 0 && !sleep(r, delay, "health") {
+			return
+		}
+
+		if shouldFail {
+			faults := healthScenario.ComponentFaults()
+			report := h.svcCtx.Health.Report(faults)
+			if faults == nil {
+				report.Status = health.Unhealthy
+			}
+
+			code := h.svcCtx.Health.StatusCode(report, statusCode)
+			if code >= http.StatusBadRequest {
+				metrics.HealthFailures.Inc(probe, strconv.Itoa(code))
+			}
+			w.WriteHeader(code)
+			httpx.OkJsonCtx(r.Context(), w, report)
+			return
+		}
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, h.svcCtx.Health.Report(nil))
 }
 
 func (h *HealthHandler) MockService(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +111,7 @@ func (h *HealthHandler) MockService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	failureType, active := depScenario.GetFailureType()
+	failureType, delay, active := depScenario.GetFailureType()
 	if !active {
 		httpx.OkJsonCtx(r.Context(), w, map[string]string{
 			"status": "ok",
@@ -84,16 +119,21 @@ func (h *HealthHandler) MockService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metrics.DependencyErrors.Inc(failureType)
 	switch failureType {
 	case "timeout":
-		time.Sleep(30 * time.Second)
+		if !sleep(r, delay, "dependency") {
+			return
+		}
 		httpx.OkJsonCtx(r.Context(), w, map[string]string{
 			"status": "ok",
 		})
 	case "error":
 		httpx.ErrorCtx(r.Context(), w, http.ErrAbortHandler)
 	case "slow":
-		time.Sleep(3 * time.Second)
+		if !sleep(r, delay, "dependency") {
+			return
+		}
 		httpx.OkJsonCtx(r.Context(), w, map[string]string{
 			"status": "ok",
 		})
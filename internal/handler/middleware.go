@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
-	"time"
+	"strconv"
 
+	"github.com/Z3Labs/MockServer/internal/metrics"
 	"github.com/Z3Labs/MockServer/internal/scenarios"
 	"github.com/Z3Labs/MockServer/internal/svc"
+	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 func LatencyMiddleware(svcCtx *svc.ServiceContext) func(next http.HandlerFunc) http.HandlerFunc {
@@ -14,9 +17,76 @@ func LatencyMiddleware(svcCtx *svc.ServiceContext) func(next http.HandlerFunc) h
 			scenario, ok := svcCtx.ScenarioManager.GetScenario("network_latency")
 			if ok {
 				if latencyScenario, ok := scenario.(*scenarios.NetworkLatency); ok {
-					delay := latencyScenario.GetLatency()
+					delay := latencyScenario.GetLatency(r)
 					if delay > 0 {
-						time.Sleep(delay)
+						metrics.InjectedLatency.Observe(delay.Milliseconds())
+						if !sleep(r, delay, "latency") {
+							latencyScenario.RecordAbandoned()
+							return
+						}
+					}
+				}
+			}
+			next(w, r)
+		}
+	}
+}
+
+func ErrorMiddleware(svcCtx *svc.ServiceContext) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			scenario, ok := svcCtx.ScenarioManager.GetScenario("http_errors")
+			if ok {
+				if errorsScenario, ok := scenario.(*scenarios.HTTPErrors); ok {
+					if injected := errorsScenario.GetError(r); injected != nil {
+						metrics.HTTPErrors.Inc(strconv.Itoa(injected.Code))
+						writeInjectedError(w, r, injected)
+						return
+					}
+				}
+			}
+			next(w, r)
+		}
+	}
+}
+
+// writeInjectedError serves the custom body as is, as JSON when it parses as
+// JSON, and a JSON error naming the status otherwise.
+func writeInjectedError(w http.ResponseWriter, r *http.Request, injected *scenarios.InjectedError) {
+	if injected.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(injected.RetryAfter))
+	}
+
+	if injected.Body == "" {
+		httpx.WriteJsonCtx(r.Context(), w, injected.Code, map[string]interface{}{
+			"error": http.StatusText(injected.Code),
+			"code":  injected.Code,
+		})
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid([]byte(injected.Body)) {
+		contentType = "application/json; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(injected.Code)
+	w.Write([]byte(injected.Body))
+}
+
+func ConnectionFaultMiddleware(svcCtx *svc.ServiceContext) func(next http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			scenario, ok := svcCtx.ScenarioManager.GetScenario("connection_faults")
+			if ok {
+				if connScenario, ok := scenario.(*scenarios.ConnectionFaults); ok {
+					if fault := connScenario.GetFault(r); fault != nil {
+						metrics.ConnectionFaults.Inc(fault.Mode)
+						if fault.Mode == scenarios.ConnHang {
+							defer connScenario.TrackHang()()
+						}
+						breakConnection(w, r, fault)
+						return
 					}
 				}
 			}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/Z3Labs/MockServer/internal/manager"
@@ -22,27 +24,35 @@ func (h *ScenarioHandler) StartScenario(w http.ResponseWriter, r *http.Request)
 	var pathParams struct {
 		Scenario string `path:"scenario"`
 	}
-	if err := httpx.Parse(r, &pathParams); err != nil {
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
 		httpx.ErrorCtx(r.Context(), w, err)
 		return
 	}
 
-	var req map[string]interface{}
-	if err := httpx.Parse(r, &req); err != nil {
+	req := make(map[string]interface{})
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 		httpx.ErrorCtx(r.Context(), w, err)
 		return
 	}
 
-	configs := []manager.ScenarioConfig{
-		{
-			Name:   pathParams.Scenario,
-			Params: req,
-		},
+	var duration int
+	if d, ok := req["duration"].(float64); ok {
+		duration = int(d)
+		delete(req, "duration")
 	}
 
-	resp, err := h.svcCtx.ScenarioManager.StartComposite(configs)
+	resp, err := h.svcCtx.ScenarioManager.StartComposite(manager.CompositeScenarioReq{
+		SessionId: "scenario-" + pathParams.Scenario,
+		Scenarios: []manager.ScenarioConfig{
+			{
+				Name:     pathParams.Scenario,
+				Params:   req,
+				Duration: duration,
+			},
+		},
+	})
 	if err != nil {
-		httpx.ErrorCtx(r.Context(), w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -98,18 +108,15 @@ func (h *ScenarioHandler) ListScenarios(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ScenarioHandler) StartCompositeScenario(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Scenarios []manager.ScenarioConfig `json:"scenarios"`
-	}
-
+	var req manager.CompositeScenarioReq
 	if err := httpx.Parse(r, &req); err != nil {
 		httpx.ErrorCtx(r.Context(), w, err)
 		return
 	}
 
-	resp, err := h.svcCtx.ScenarioManager.StartComposite(req.Scenarios)
+	resp, err := h.svcCtx.ScenarioManager.StartComposite(req)
 	if err != nil {
-		httpx.ErrorCtx(r.Context(), w, err)
+		writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Z3Labs/MockServer/internal/manager"
+	"github.com/Z3Labs/MockServer/internal/svc"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+type SessionHandler struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewSessionHandler(svcCtx *svc.ServiceContext) *SessionHandler {
+	return &SessionHandler{
+		svcCtx: svcCtx,
+	}
+}
+
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := h.svcCtx.ScenarioManager.ListSessions()
+
+	httpx.OkJsonCtx(r.Context(), w, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	var pathParams struct {
+		Id string `path:"id"`
+	}
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	resp, err := h.svcCtx.ScenarioManager.GetSession(pathParams.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, resp)
+}
+
+func (h *SessionHandler) StopSession(w http.ResponseWriter, r *http.Request) {
+	var pathParams struct {
+		Id string `path:"id"`
+	}
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	resp, err := h.svcCtx.ScenarioManager.StopSession(pathParams.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, resp)
+}
+
+func (h *SessionHandler) ExtendSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id      string `path:"id"`
+		Seconds int    `json:"seconds"`
+	}
+	if err := httpx.Parse(r, &req); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	resp, err := h.svcCtx.ScenarioManager.ExtendSession(req.Id, req.Seconds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, resp)
+}
+
+func (h *SessionHandler) SessionHistory(w http.ResponseWriter, r *http.Request) {
+	sessions := h.svcCtx.ScenarioManager.SessionHistory()
+
+	httpx.OkJsonCtx(r.Context(), w, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+func (h *SessionHandler) SessionReport(w http.ResponseWriter, r *http.Request) {
+	var pathParams struct {
+		Id string `path:"id"`
+	}
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	report, err := h.svcCtx.ScenarioManager.GetSessionReport(pathParams.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, report)
+}
+
+func (h *SessionHandler) SetSessionLabels(w http.ResponseWriter, r *http.Request) {
+	var pathParams struct {
+		Id string `path:"id"`
+	}
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	var labels manager.SessionLabels
+	if err := httpx.ParseJsonBody(r, &labels); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	resp, err := h.svcCtx.ScenarioManager.SetSessionLabels(pathParams.Id, labels)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, resp)
+}
+
+func (h *SessionHandler) AnswerKey(w http.ResponseWriter, r *http.Request) {
+	var pathParams struct {
+		Id string `path:"id"`
+	}
+	if err := httpx.ParsePath(r, &pathParams); err != nil {
+		httpx.ErrorCtx(r.Context(), w, err)
+		return
+	}
+
+	key, err := h.svcCtx.ScenarioManager.GetAnswerKey(pathParams.Id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	httpx.OkJsonCtx(r.Context(), w, key)
+}
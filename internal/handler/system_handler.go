@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Z3Labs/MockServer/internal/svc"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+type SystemHandler struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewSystemHandler(svcCtx *svc.ServiceContext) *SystemHandler {
+	return &SystemHandler{
+		svcCtx: svcCtx,
+	}
+}
+
+func (h *SystemHandler) GetSystem(w http.ResponseWriter, r *http.Request) {
+	httpx.OkJsonCtx(r.Context(), w, map[string]interface{}{
+		"limits": h.svcCtx.Limits,
+	})
+}
+
+func (h *SystemHandler) LastExit(w http.ResponseWriter, r *http.Request) {
+	httpx.OkJsonCtx(r.Context(), w, h.svcCtx.LastExit)
+}
@@ -0,0 +1,92 @@
+package health
+
+import "github.com/Z3Labs/MockServer/internal/config"
+
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+
+	Healthy   = "healthy"
+	Degraded  = "degraded"
+	Unhealthy = "unhealthy"
+)
+
+var defaultComponents = []config.ComponentConf{
+	{Name: "db", Critical: true, LatencyMs: 2},
+	{Name: "cache", LatencyMs: 1},
+	{Name: "downstream", LatencyMs: 15},
+	{Name: "disk", Critical: true, LatencyMs: 1},
+}
+
+// Check is the result of one component check.
+type Check struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// Report is the body of a health check response.
+type Report struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Model holds the components the service depends on and the rules that
+// derive the overall status from their checks.
+type Model struct {
+	components         []config.ComponentConf
+	degradedStatusCode int
+}
+
+func NewModel(c config.HealthConf) *Model {
+	components := c.Components
+	if len(components) == 0 {
+		components = defaultComponents
+	}
+
+	return &Model{
+		components:         components,
+		degradedStatusCode: c.DegradedStatusCode,
+	}
+}
+
+// Report checks every component, using faults for the failed ones. A
+// critical component that is down makes the service unhealthy, any other
+// failure degrades it.
+func (m *Model) Report(faults map[string]Check) Report {
+	report := Report{
+		Status: Healthy,
+		Checks: make(map[string]Check, len(m.components)),
+	}
+
+	for _, component := range m.components {
+		check, ok := faults[component.Name]
+		if !ok {
+			check = Check{Status: StatusUp, LatencyMs: component.LatencyMs}
+		}
+		report.Checks[component.Name] = check
+
+		switch {
+		case check.Status == StatusDown && component.Critical:
+			report.Status = Unhealthy
+		case check.Status != StatusUp && report.Status == Healthy:
+			report.Status = Degraded
+		}
+	}
+
+	return report
+}
+
+// StatusCode returns the HTTP status code for report, failCode being the one
+// of an unhealthy service.
+func (m *Model) StatusCode(report Report, failCode int) int {
+	switch report.Status {
+	case Unhealthy:
+		return failCode
+	case Degraded:
+		return m.degradedStatusCode
+	default:
+		return 200
+	}
+}
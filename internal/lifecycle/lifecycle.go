@@ -0,0 +1,178 @@
+package lifecycle
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+const (
+	// drainTime is how long readiness fails before the server stops
+	// accepting connections, so load balancers can take it out of rotation.
+	drainTime = time.Second
+	// forceQuitTime bounds a graceful shutdown, like go-zero's own default.
+	forceQuitTime = 5500 * time.Millisecond
+)
+
+// Action is the reaction to a termination signal.
+type Action int
+
+const (
+	// Graceful fails readiness, drains in-flight requests and exits.
+	Graceful Action = iota
+	// Ignore keeps running as if the signal never arrived.
+	Ignore
+	// Hang fails readiness but keeps serving for a while, then exits
+	// gracefully.
+	Hang
+	// Drop closes every connection at once, dropping in-flight requests.
+	Drop
+	// NotReady fails readiness but keeps serving and never exits.
+	NotReady
+)
+
+func (a Action) String() string {
+	switch a {
+	case Ignore:
+		return "ignore"
+	case Hang:
+		return "hang"
+	case Drop:
+		return "drop"
+	case NotReady:
+		return "not_ready"
+	default:
+		return "graceful"
+	}
+}
+
+// Hook lets a scenario take over the reaction to SIGTERM and SIGINT. The
+// duration is only used by Hang.
+type Hook interface {
+	OnTerminate(sig os.Signal) (Action, time.Duration)
+}
+
+// Lifecycle owns the process's reaction to termination signals, replacing the
+// handler go-zero installs, and the readiness that goes with it.
+type Lifecycle struct {
+	mu            sync.Mutex
+	hooks         []Hook
+	server        *http.Server
+	ready         atomic.Bool
+	shutdown      sync.Once
+	notReadyUntil atomic.Int64
+}
+
+func New() *Lifecycle {
+	l := &Lifecycle{}
+	l.ready.Store(true)
+	return l
+}
+
+func (l *Lifecycle) AddHook(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Ready reports false once a termination signal made the process fail its
+// readiness probe.
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
+
+// Starting reports whether a startup fault still holds readiness back.
+func (l *Lifecycle) Starting() bool {
+	return time.Now().UnixNano() < l.notReadyUntil.Load()
+}
+
+// StartOption captures the http.Server so that Drop can close it.
+func (l *Lifecycle) StartOption() rest.StartOption {
+	return func(svr *http.Server) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.server = svr
+	}
+}
+
+// Listen takes over SIGTERM and SIGINT from go-zero. Its proc package keeps
+// subscribing to them, so its shutdown is pushed back past any lifetime, and
+// our own subscription decides what happens instead. Listen must run after
+// rest.MustNewServer, which sets the shutdown times up from the config.
+func (l *Lifecycle) Listen() {
+	disableProcShutdown()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		for sig := range signals {
+			l.handle(sig)
+		}
+	}()
+}
+
+func (l *Lifecycle) handle(sig os.Signal) {
+	action, delay := l.action(sig)
+	logx.Infof("got signal %v, reaction: %s", sig, action)
+
+	switch action {
+	case Ignore:
+	case NotReady:
+		l.ready.Store(false)
+	case Hang:
+		l.ready.Store(false)
+		time.AfterFunc(delay, func() {
+			l.stop(false)
+		})
+	case Drop:
+		go l.stop(true)
+	default:
+		go l.stop(false)
+	}
+}
+
+func (l *Lifecycle) action(sig os.Signal) (Action, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, hook := range l.hooks {
+		if action, delay := hook.OnTerminate(sig); action != Graceful {
+			return action, delay
+		}
+	}
+	return Graceful, 0
+}
+
+func (l *Lifecycle) stop(drop bool) {
+	l.shutdown.Do(func() {
+		l.ready.Store(false)
+
+		if drop {
+			l.mu.Lock()
+			server := l.server
+			l.mu.Unlock()
+			if server != nil {
+				server.Close()
+			}
+		} else {
+			time.Sleep(drainTime)
+		}
+
+		time.AfterFunc(forceQuitTime, func() {
+			logx.Infof("still alive after %v, exiting", forceQuitTime)
+			os.Exit(1)
+		})
+		// The shutdown listeners stop the http.Server and let Start return.
+		proc.WrapUp()
+		proc.Shutdown()
+	})
+}
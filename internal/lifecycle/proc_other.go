@@ -0,0 +1,6 @@
+//go:build !linux && !darwin
+
+package lifecycle
+
+// disableProcShutdown does nothing where go-zero handles no signals.
+func disableProcShutdown() {}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package lifecycle
+
+import (
+	"math"
+
+	"github.com/zeromicro/go-zero/core/proc"
+)
+
+// disableProcShutdown keeps go-zero from shutting down or killing the
+// process when it sees SIGTERM or SIGINT.
+func disableProcShutdown() {
+	proc.Setup(proc.ShutdownConf{
+		WrapUpTime: math.MaxInt64,
+		WaitTime:   math.MaxInt64,
+	})
+}
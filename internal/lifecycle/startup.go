@@ -0,0 +1,36 @@
+package lifecycle
+
+import (
+	"os"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/config"
+	"github.com/Z3Labs/MockServer/internal/system"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Startup applies the startup faults that act before the port is bound. It
+// blocks for the bind delay and does not return on a startup failure.
+func (l *Lifecycle) Startup(c config.StartupConf, stateDir string) {
+	boot := time.Now()
+
+	if c.Fail {
+		system.WriteExitMarker(stateDir, system.ExitMarker{
+			Reason:           "startup_config_error",
+			ExpectedExitCode: c.FailExitCode,
+		})
+		logx.Error("invalid configuration: Database.DataSource: missing required field")
+		logx.Close()
+		os.Exit(c.FailExitCode)
+	}
+
+	if c.NotReadyFor > 0 {
+		l.notReadyUntil.Store(boot.Add(time.Duration(c.NotReadyFor) * time.Second).UnixNano())
+		logx.Infof("startup: not ready for %ds", c.NotReadyFor)
+	}
+
+	if c.BindDelay > 0 {
+		logx.Infof("startup: delaying port binding by %ds", c.BindDelay)
+		time.Sleep(time.Duration(c.BindDelay) * time.Second)
+	}
+}
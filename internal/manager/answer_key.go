@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+)
+
+// SessionLabels is what the caller expects a diagnosis system to conclude
+// about a session. Labels take precedence over the derived diagnosis.
+type SessionLabels struct {
+	RootCause         string            `json:"root_cause,optional,omitempty"`
+	Category          string            `json:"category,optional,omitempty"`
+	AffectedComponent string            `json:"affected_component,optional,omitempty"`
+	ExpectedSymptoms  []string          `json:"expected_symptoms,optional,omitempty"`
+	Extra             map[string]string `json:"extra,optional,omitempty"`
+}
+
+// merge overlays the non-empty fields of update onto l.
+func (l *SessionLabels) merge(update SessionLabels) *SessionLabels {
+	merged := SessionLabels{}
+	if l != nil {
+		merged = *l
+	}
+
+	if update.RootCause != "" {
+		merged.RootCause = update.RootCause
+	}
+	if update.Category != "" {
+		merged.Category = update.Category
+	}
+	if update.AffectedComponent != "" {
+		merged.AffectedComponent = update.AffectedComponent
+	}
+	if len(update.ExpectedSymptoms) > 0 {
+		merged.ExpectedSymptoms = update.ExpectedSymptoms
+	}
+	if len(update.Extra) > 0 {
+		extra := make(map[string]string, len(merged.Extra)+len(update.Extra))
+		for k, v := range merged.Extra {
+			extra[k] = v
+		}
+		for k, v := range update.Extra {
+			extra[k] = v
+		}
+		merged.Extra = extra
+	}
+
+	return &merged
+}
+
+// AnswerKey is the machine readable grading reference for a session.
+type AnswerKey struct {
+	SessionId string            `json:"session_id"`
+	Status    string            `json:"status"`
+	StartTime time.Time         `json:"start_time"`
+	EndTime   *time.Time        `json:"end_time,omitempty"`
+	Labels    *SessionLabels    `json:"labels,omitempty"`
+	Expected  ExpectedDiagnosis `json:"expected"`
+	Faults    []InjectedFault   `json:"faults"`
+}
+
+// ExpectedDiagnosis merges the caller labels with the diagnosis of every
+// fault that was actually injected.
+type ExpectedDiagnosis struct {
+	RootCauses []string `json:"root_causes"`
+	Categories []string `json:"categories"`
+	Components []string `json:"components"`
+	Symptoms   []string `json:"symptoms"`
+}
+
+type InjectedFault struct {
+	Scenario  string                 `json:"scenario"`
+	Params    map[string]interface{} `json:"params"`
+	StartedAt *time.Time             `json:"started_at,omitempty"`
+	StoppedAt *time.Time             `json:"stopped_at,omitempty"`
+	scenarios.Diagnosis
+}
+
+// SetSessionLabels merges labels into a live or finished session.
+func (sm *ScenarioManager) SetSessionLabels(id string, labels SessionLabels) (*SessionLabels, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if session, ok := sm.sessions[id]; ok {
+		session.labels = session.labels.merge(labels)
+		return session.labels, nil
+	}
+
+	report, ok := sm.history.get(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	report.Labels = report.Labels.merge(labels)
+	sm.history.save()
+
+	return report.Labels, nil
+}
+
+func (sm *ScenarioManager) GetAnswerKey(id string) (*AnswerKey, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	report, ok := sm.history.get(id)
+	if session, live := sm.sessions[id]; live {
+		report, ok = session.report(time.Now()), true
+	}
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	key := &AnswerKey{
+		SessionId: report.SessionId,
+		Status:    report.Status,
+		StartTime: report.StartTime,
+		EndTime:   report.EndTime,
+		Labels:    report.Labels,
+		Faults:    []InjectedFault{},
+	}
+
+	expected := newDiagnosisSet()
+	if labels := report.Labels; labels != nil {
+		expected.add(scenarios.Diagnosis{
+			Category:  labels.Category,
+			RootCause: labels.RootCause,
+			Component: labels.AffectedComponent,
+			Symptoms:  labels.ExpectedSymptoms,
+		})
+	}
+
+	for _, detail := range report.Details {
+		if detail.StartedAt == nil || detail.State == StepFailed {
+			continue
+		}
+		scenario, ok := sm.scenarios[detail.Name]
+		if !ok {
+			continue
+		}
+
+		diagnosis := scenario.Diagnosis(detail.Params)
+		expected.add(diagnosis)
+		key.Faults = append(key.Faults, InjectedFault{
+			Scenario:  detail.Name,
+			Params:    detail.Params,
+			StartedAt: detail.StartedAt,
+			StoppedAt: detail.StoppedAt,
+			Diagnosis: diagnosis,
+		})
+	}
+
+	key.Expected = expected.ExpectedDiagnosis
+	return key, nil
+}
+
+// diagnosisSet accumulates diagnoses without repeating values, keeping the
+// order in which they were first seen.
+type diagnosisSet struct {
+	ExpectedDiagnosis
+	seen map[string]bool
+}
+
+func newDiagnosisSet() *diagnosisSet {
+	return &diagnosisSet{
+		ExpectedDiagnosis: ExpectedDiagnosis{
+			RootCauses: []string{},
+			Categories: []string{},
+			Components: []string{},
+			Symptoms:   []string{},
+		},
+		seen: make(map[string]bool),
+	}
+}
+
+func (d *diagnosisSet) add(diagnosis scenarios.Diagnosis) {
+	d.RootCauses = d.appendNew(d.RootCauses, "root_cause", diagnosis.RootCause)
+	d.Categories = d.appendNew(d.Categories, "category", diagnosis.Category)
+	d.Components = d.appendNew(d.Components, "component", diagnosis.Component)
+	for _, symptom := range diagnosis.Symptoms {
+		d.Symptoms = d.appendNew(d.Symptoms, "symptom", symptom)
+	}
+}
+
+func (d *diagnosisSet) appendNew(list []string, kind, value string) []string {
+	if value == "" || d.seen[kind+":"+value] {
+		return list
+	}
+	d.seen[kind+":"+value] = true
+	return append(list, value)
+}
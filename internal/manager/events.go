@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventSessionStarted        = "session_started"
+	EventSessionCompleted      = "session_completed"
+	EventSessionStopped        = "session_stopped"
+	EventScenarioStarted       = "scenario_started"
+	EventScenarioStopped       = "scenario_stopped"
+	EventScenarioFailed        = "scenario_failed"
+	EventScenarioParamsChanged = "scenario_params_changed"
+	EventScenarioTargetReached = "scenario_target_reached"
+	EventScenarioCrashImminent = "scenario_crash_imminent"
+)
+
+const (
+	eventBacklog      = 256
+	subscriberBacklog = 64
+)
+
+// Event is a state change of a session or of one of its scenarios.
+type Event struct {
+	Id             int64                  `json:"id"`
+	Type           string                 `json:"type"`
+	Time           time.Time              `json:"time"`
+	SessionId      string                 `json:"session_id,omitempty"`
+	Scenario       string                 `json:"scenario,omitempty"`
+	Reason         string                 `json:"reason,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	Params         map[string]interface{} `json:"params,omitempty"`
+	PreviousParams map[string]interface{} `json:"previous_params,omitempty"`
+	Metrics        map[string]float64     `json:"metrics,omitempty"`
+}
+
+// EventBus fans events out to subscribers and keeps a short backlog so that
+// reconnecting clients can catch up on what they missed.
+type EventBus struct {
+	mu          sync.Mutex
+	nextId      int64
+	backlog     []Event
+	subscribers map[chan Event]struct{}
+	handlers    []func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish never blocks on subscribers: those that fall behind lose events
+// rather than stalling the scenario manager. Handlers run after the bus is
+// unlocked, so a slow handler only holds up its own publisher.
+func (b *EventBus) Publish(event Event) {
+	b.mu.Lock()
+	b.nextId++
+	event.Id = b.nextId
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.backlog = append(b.backlog, event)
+	if len(b.backlog) > eventBacklog {
+		b.backlog = b.backlog[len(b.backlog)-eventBacklog:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	handlers := b.handlers
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// OnPublish registers a handler that runs synchronously inside Publish, for
+// consumers that must see an event before the publisher carries on, such as
+// before the process exits. Handlers run concurrently when several goroutines
+// publish and should hand work off quickly: most publishers hold sm.mu.
+func (b *EventBus) OnPublish(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(append([]func(Event){}, b.handlers...), handler)
+}
+
+// Subscribe returns a channel of future events and, when afterId is set, the
+// backlogged events newer than it. The returned func unsubscribes.
+func (b *EventBus) Subscribe(afterId int64) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	for _, event := range b.backlog {
+		if afterId > 0 && event.Id > afterId {
+			missed = append(missed, event)
+		}
+	}
+
+	ch := make(chan Event, subscriberBacklog)
+	b.subscribers[ch] = struct{}{}
+
+	return missed, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, ch)
+	}
+}
+
+// publishStep reports a state change of a step. Callers must hold sm.mu.
+func (sm *ScenarioManager) publishStep(eventType string, session *ScenarioSession, step *sessionStep) {
+	sm.events.Publish(Event{
+		Type:      eventType,
+		SessionId: session.SessionId,
+		Scenario:  step.name,
+		Reason:    step.stopReason,
+		Error:     step.err,
+		Params:    step.params,
+		Metrics:   step.finalMetrics,
+	})
+}
+
+// publishSession reports the end of a session. Callers must hold sm.mu.
+func (sm *ScenarioManager) publishSession(session *ScenarioSession, status, reason string) {
+	eventType := EventSessionStopped
+	if status == "completed" {
+		eventType = EventSessionCompleted
+	}
+	sm.events.Publish(Event{
+		Type:      eventType,
+		SessionId: session.SessionId,
+		Reason:    reason,
+	})
+}
+
+// publishNotify forwards a milestone reported by a scenario, attributing it to
+// the session that is running the scenario. It publishes after releasing
+// sm.mu, because handlers of some events, such as a crash, block for seconds.
+func (sm *ScenarioManager) publishNotify(name, event string, metrics map[string]float64) {
+	ev := Event{Type: "scenario_" + event, Scenario: name, Metrics: metrics}
+
+	sm.mu.RLock()
+	for _, session := range sm.sessions {
+		for _, step := range session.steps {
+			if step.name == name && step.state == StepRunning {
+				ev.SessionId = session.SessionId
+				ev.Params = step.params
+			}
+		}
+	}
+	sm.mu.RUnlock()
+
+	sm.events.Publish(ev)
+}
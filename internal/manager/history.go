@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// SessionReport is the ground-truth record of what a session injected and
+// when, kept after the session has ended.
+type SessionReport struct {
+	SessionId  string           `json:"session_id"`
+	Status     string           `json:"status"`
+	StopReason string           `json:"stop_reason,omitempty"`
+	StartTime  time.Time        `json:"start_time"`
+	EndTime    *time.Time       `json:"end_time,omitempty"`
+	Scenarios  []string         `json:"scenarios"`
+	Labels     *SessionLabels   `json:"labels,omitempty"`
+	Details    []ScenarioDetail `json:"details"`
+	Samples    []MetricSample   `json:"samples,omitempty"`
+}
+
+// MetricSample holds the metrics of every running scenario at one point in time.
+type MetricSample struct {
+	Time    time.Time                     `json:"time"`
+	Metrics map[string]map[string]float64 `json:"metrics"`
+}
+
+type SessionSummary struct {
+	SessionId  string     `json:"session_id"`
+	Status     string     `json:"status"`
+	StopReason string     `json:"stop_reason,omitempty"`
+	StartTime  time.Time  `json:"start_time"`
+	EndTime    *time.Time `json:"end_time,omitempty"`
+	Scenarios  []string   `json:"scenarios"`
+}
+
+// sessionHistory keeps the most recent finished sessions, optionally
+// mirrored to a JSON file so that they survive restarts.
+type sessionHistory struct {
+	maxSessions int
+	file        string
+	reports     []*SessionReport
+}
+
+func newSessionHistory(maxSessions int, file string) *sessionHistory {
+	h := &sessionHistory{
+		maxSessions: maxSessions,
+		file:        file,
+	}
+
+	if file == "" {
+		return h
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logx.Errorf("read session history %s: %v", file, err)
+		}
+		return h
+	}
+	if err := json.Unmarshal(data, &h.reports); err != nil {
+		logx.Errorf("parse session history %s: %v", file, err)
+		h.reports = nil
+	}
+	h.trim()
+
+	return h
+}
+
+func (h *sessionHistory) add(report *SessionReport) {
+	h.reports = append(h.reports, report)
+	h.trim()
+	h.save()
+}
+
+func (h *sessionHistory) trim() {
+	if h.maxSessions > 0 && len(h.reports) > h.maxSessions {
+		h.reports = h.reports[len(h.reports)-h.maxSessions:]
+	}
+}
+
+func (h *sessionHistory) save() {
+	if h.file == "" {
+		return
+	}
+
+	data, err := json.Marshal(h.reports)
+	if err != nil {
+		logx.Errorf("encode session history: %v", err)
+		return
+	}
+
+	tmp := h.file + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(h.file), 0o755); err != nil {
+		logx.Errorf("create session history dir: %v", err)
+		return
+	}
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		logx.Errorf("write session history %s: %v", tmp, err)
+		return
+	}
+	if err := os.Rename(tmp, h.file); err != nil {
+		logx.Errorf("replace session history %s: %v", h.file, err)
+	}
+}
+
+// get returns the most recent report for id, since named sessions can be
+// started more than once.
+func (h *sessionHistory) get(id string) (*SessionReport, bool) {
+	for i := len(h.reports) - 1; i >= 0; i-- {
+		if h.reports[i].SessionId == id {
+			return h.reports[i], true
+		}
+	}
+	return nil, false
+}
+
+func (h *sessionHistory) list() []SessionSummary {
+	list := make([]SessionSummary, 0, len(h.reports))
+	for i := len(h.reports) - 1; i >= 0; i-- {
+		report := h.reports[i]
+		list = append(list, SessionSummary{
+			SessionId:  report.SessionId,
+			Status:     report.Status,
+			StopReason: report.StopReason,
+			StartTime:  report.StartTime,
+			EndTime:    report.EndTime,
+			Scenarios:  report.Scenarios,
+		})
+	}
+	return list
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSessionHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+	h := newSessionHistory(3, file)
+	for i, id := range []string{"s1", "s2", "s1", "s3", "s4"} {
+		h.add(&SessionReport{SessionId: id, Status: "stopped", StopReason: strconv.Itoa(i)})
+	}
+
+	ids := func(h *sessionHistory) []string {
+		var list []string
+		for _, summary := range h.list() {
+			list = append(list, summary.SessionId)
+		}
+		return list
+	}
+	if got, want := ids(h), []string{"s4", "s3", "s1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if report, ok := h.get("s1"); !ok || report.StopReason != "2" {
+		t.Errorf("get(s1) = %+v, %v, want the most recent s1", report, ok)
+	}
+	if _, ok := h.get("s2"); ok {
+		t.Error("get(s2) found a session trimmed from the history")
+	}
+
+	loaded := newSessionHistory(2, file)
+	if got, want := ids(loaded), []string{"s4", "s3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded list = %v, want %v", got, want)
+	}
+}
+
+func TestSessionEndReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []*sessionStep
+		want  string
+	}{
+		{
+			name:  "completed",
+			steps: []*sessionStep{{state: StepStopped, stopReason: StopReasonExpired}, {state: StepStopped, stopReason: StopReasonSuperseded}},
+			want:  "",
+		},
+		{
+			name:  "stopped by hand",
+			steps: []*sessionStep{{state: StepStopped, stopReason: StopReasonExpired}, {state: StepStopped, stopReason: StopReasonManual}},
+			want:  StopReasonManual,
+		},
+		{
+			name:  "nothing started",
+			steps: []*sessionStep{{state: StepFailed, err: "cannot start"}},
+			want:  StopReasonFailed,
+		},
+		{
+			name:  "some failed",
+			steps: []*sessionStep{{state: StepFailed, err: "cannot start"}, {state: StepStopped, stopReason: StopReasonExpired}},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &ScenarioSession{steps: tt.steps}
+			if got := session.endReason(); got != tt.want {
+				t.Errorf("endReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepDetailSuccess(t *testing.T) {
+	tests := []struct {
+		name string
+		step *sessionStep
+		want bool
+	}{
+		{name: "scheduled", step: &sessionStep{state: StepScheduled}, want: true},
+		{name: "running", step: &sessionStep{state: StepRunning}, want: true},
+		{name: "expired", step: &sessionStep{state: StepStopped, stopReason: StopReasonExpired}, want: true},
+		{name: "stopped by hand", step: &sessionStep{state: StepStopped, stopReason: StopReasonManual}, want: true},
+		{name: "cancelled", step: &sessionStep{state: StepCancelled, stopReason: StopReasonManual}, want: true},
+		{name: "failed to start", step: &sessionStep{state: StepFailed, err: "cannot start"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newTestSession(nil, 0)
+			if got := tt.step.detail(session, session.StartTime).Success; got != tt.want {
+				t.Errorf("Success = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopSessionReport(t *testing.T) {
+	sm := newTestManager(t)
+	_, err := sm.StartComposite(CompositeScenarioReq{
+		SessionId: "s1",
+		Scenarios: []ScenarioConfig{{Name: "a"}, {Name: "b", StartAfter: 60}, {Name: "broken"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.StopSession("s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := sm.GetSessionReport("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != "stopped" || report.StopReason != StopReasonManual || report.EndTime == nil {
+		t.Errorf("report = %s/%s ended %v, want stopped/manual with an end time", report.Status, report.StopReason, report.EndTime)
+	}
+
+	var states []string
+	for _, detail := range report.Details {
+		states = append(states, detail.Name+":"+detail.State)
+	}
+	if want := []string{"a:stopped", "broken:failed", "b:cancelled"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+	if got := report.Details[0].Params["level"]; got != 1.0 {
+		t.Errorf("a level = %v, want the default 1", got)
+	}
+}
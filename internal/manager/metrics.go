@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/metrics"
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+)
+
+const metricsInterval = time.Second
+
+// exportMetrics publishes the state of every scenario to Prometheus so that
+// dashboards can overlay injected faults on the symptoms they cause.
+func (sm *ScenarioManager) exportMetrics() {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sm.publishMetrics()
+	}
+}
+
+// publishMetrics collects the statuses outside sm.mu, so that a slow Status
+// does not hold up the sessions.
+func (sm *ScenarioManager) publishMetrics() {
+	sm.mu.RLock()
+	sessionStart := make(map[string]time.Time)
+	for _, session := range sm.sessions {
+		for _, step := range session.steps {
+			if step.state == StepRunning {
+				sessionStart[step.name] = session.StartTime
+			}
+		}
+	}
+	sessions := len(sm.sessions)
+	all := make(map[string]scenarios.Scenario, len(sm.scenarios))
+	for name, scenario := range sm.scenarios {
+		all[name] = scenario
+	}
+	sm.mu.RUnlock()
+
+	metrics.SessionsActive.Set(float64(sessions))
+
+	for name, scenario := range all {
+		status := scenario.Status()
+
+		active := 0.0
+		if status.Running {
+			active = 1
+		}
+		metrics.ScenarioActive.Set(active, name)
+
+		var start float64
+		if t, ok := sessionStart[name]; ok && status.Running {
+			start = float64(t.UnixNano()) / float64(time.Second)
+		}
+		metrics.ScenarioSessionStart.Set(start, name)
+
+		// Gauges cannot be removed, so values of an idle scenario drop to 0
+		// instead of keeping the last injected value.
+		for key, value := range status.Metrics {
+			if !status.Running {
+				value = 0
+			}
+			metrics.ScenarioValue.Set(value, name, key)
+		}
+	}
+}
@@ -6,77 +6,163 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Z3Labs/MockServer/internal/config"
 	"github.com/Z3Labs/MockServer/internal/scenarios"
+	"github.com/Z3Labs/MockServer/internal/system"
+
+	"github.com/zeromicro/go-zero/core/logx"
 )
 
 type ScenarioManager struct {
 	scenarios      map[string]scenarios.Scenario
-	currentSession *ScenarioSession
+	sessions       map[string]*ScenarioSession
+	history        *sessionHistory
+	events         *EventBus
+	sampleInterval time.Duration
+	maxSamples     int
+	stateDir       string
+	startup        config.StartupConf
 	mu             sync.RWMutex
 }
 
-type ScenarioSession struct {
-	SessionId     string
-	Scenarios     []string
-	StartTime     time.Time
-	CancelFunc    context.CancelFunc
-	RecoveryTimer *time.Timer
-}
-
 type ScenarioInfo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Running     bool   `json:"running"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Running     bool                  `json:"running"`
+	Params      scenarios.ParamSchema `json:"params"`
 }
 
-func NewScenarioManager() *ScenarioManager {
+func NewScenarioManager(c config.Config, limits system.Limits) *ScenarioManager {
+	sampleInterval := time.Duration(c.History.SampleInterval) * time.Second
+	if sampleInterval <= 0 {
+		sampleInterval = 5 * time.Second
+	}
+
 	sm := &ScenarioManager{
-		scenarios: make(map[string]scenarios.Scenario),
+		scenarios:      make(map[string]scenarios.Scenario),
+		sessions:       make(map[string]*ScenarioSession),
+		history:        newSessionHistory(c.History.MaxSessions, c.History.File),
+		events:         NewEventBus(),
+		sampleInterval: sampleInterval,
+		maxSamples:     c.History.MaxSamples,
+		stateDir:       c.System.StateDir,
+		startup:        c.Startup,
 	}
 
-	sm.registerScenarios()
+	sm.registerScenarios(limits, c.System.StateDir)
 
 	return sm
 }
 
-func (sm *ScenarioManager) registerScenarios() {
-	sm.Register(scenarios.NewCPUBurner())
-	sm.Register(scenarios.NewMemoryLeaker())
+// Run starts the sessions the manager owes to its config and state, such as a
+// crash loop left by the previous run, and the metrics export. Consumers of
+// Events must be attached before, so that they see those sessions start.
+func (sm *ScenarioManager) Run() {
+	sm.resumeCrashLoop(sm.stateDir)
+	sm.scheduleDegrade(sm.startup)
+	go sm.exportMetrics()
+}
+
+func (sm *ScenarioManager) registerScenarios(limits system.Limits, stateDir string) {
+	sm.Register(scenarios.NewCPUBurner(limits))
+	sm.Register(scenarios.NewMemoryLeaker(limits))
 	sm.Register(scenarios.NewNetworkLatency())
+	sm.Register(scenarios.NewHTTPErrors())
+	sm.Register(scenarios.NewConnectionFaults())
 	sm.Register(scenarios.NewHealthCheckFailure())
+	sm.Register(scenarios.NewProbeFailure(scenarios.ProbeLiveness))
+	sm.Register(scenarios.NewProbeFailure(scenarios.ProbeReadiness))
+	sm.Register(scenarios.NewProbeFailure(scenarios.ProbeStartup))
 	sm.Register(scenarios.NewGoroutineLeak())
 	sm.Register(scenarios.NewDiskIO())
-	sm.Register(scenarios.NewCrashSimulator())
+	sm.Register(scenarios.NewCrashSimulator(stateDir))
 	sm.Register(scenarios.NewDependencyFailure())
+	sm.Register(scenarios.NewOOMKiller(limits, stateDir))
+	sm.Register(scenarios.NewShutdownMisbehavior())
+}
+
+const (
+	// crashLoopSession is the session a resumed crash loop runs in.
+	crashLoopSession = "crash-loop"
+	// startupSession is the session startup faults run in.
+	startupSession = "startup"
+)
+
+// resumeCrashLoop restarts a crash loop left by the previous run, so the
+// process keeps crashing after every restart until the loop is stopped.
+func (sm *ScenarioManager) resumeCrashLoop(stateDir string) {
+	state, ok := scenarios.LoadCrashLoop(stateDir)
+	if !ok {
+		return
+	}
+
+	resp, err := sm.StartComposite(CompositeScenarioReq{
+		SessionId: crashLoopSession,
+		Scenarios: []ScenarioConfig{{Name: "crash", Params: state.Params}},
+	})
+	if err != nil || resp.Status == "failed" {
+		logx.Errorf("resume crash loop after %d crashes: %v", state.Crashes, err)
+		scenarios.ClearCrashLoop(stateDir)
+		return
+	}
+	logx.Infof("resumed crash loop after %d crashes", state.Crashes)
+}
+
+// scheduleDegrade starts the health_check scenario DegradeAfter seconds after
+// boot, so the service passes its first probes and then fails.
+func (sm *ScenarioManager) scheduleDegrade(c config.StartupConf) {
+	if c.DegradeAfter <= 0 {
+		return
+	}
+
+	_, err := sm.StartComposite(CompositeScenarioReq{
+		SessionId: startupSession,
+		Scenarios: []ScenarioConfig{{Name: "health_check", StartAfter: c.DegradeAfter}},
+	})
+	if err != nil {
+		logx.Errorf("schedule startup degradation: %v", err)
+		return
+	}
+	logx.Infof("startup: degrading health checks after %ds", c.DegradeAfter)
 }
 
 func (sm *ScenarioManager) Register(scenario scenarios.Scenario) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	sm.scenarios[scenario.Name()] = scenario
-}
 
-func (sm *ScenarioManager) Start(ctx context.Context, name string, params map[string]interface{}) error {
-	sm.mu.RLock()
-	scenario, ok := sm.scenarios[name]
-	sm.mu.RUnlock()
-
-	if !ok {
-		return fmt.Errorf("scenario %s not found", name)
+	if notifier, ok := scenario.(scenarios.Notifier); ok {
+		name := scenario.Name()
+		notifier.SetNotify(func(event string, metrics map[string]float64) {
+			sm.publishNotify(name, event, metrics)
+		})
 	}
+}
 
-	return scenario.Start(ctx, params)
+// Events returns the bus on which session and scenario state changes are
+// published.
+func (sm *ScenarioManager) Events() *EventBus {
+	return sm.events
 }
 
 func (sm *ScenarioManager) Stop(name string) error {
-	sm.mu.RLock()
-	scenario, ok := sm.scenarios[name]
-	sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
+	scenario, ok := sm.scenarios[name]
 	if !ok {
 		return fmt.Errorf("scenario %s not found", name)
 	}
 
+	for _, session := range sm.sessions {
+		for _, step := range session.steps {
+			if step.name == name && step.state == StepRunning {
+				sm.stopStep(session, step, StopReasonManual)
+				session.notify()
+			}
+		}
+	}
+
 	return scenario.Stop()
 }
 
@@ -103,6 +189,7 @@ func (sm *ScenarioManager) List() []ScenarioInfo {
 			Name:        name,
 			Description: scenario.Describe(),
 			Running:     status.Running,
+			Params:      scenario.Schema(),
 		})
 	}
 
@@ -116,145 +203,152 @@ func (sm *ScenarioManager) GetScenario(name string) (scenarios.Scenario, bool) {
 	return scenario, ok
 }
 
-func (sm *ScenarioManager) StartComposite(configs []ScenarioConfig) (*CompositeScenarioResp, error) {
+func (sm *ScenarioManager) StartComposite(req CompositeScenarioReq) (*CompositeScenarioResp, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	if sm.currentSession != nil {
-		sm.stopCurrentSession()
+	if req.SessionId != "" && !validSessionId(req.SessionId) {
+		return nil, &scenarios.ValidationError{Fields: []scenarios.FieldError{
+			{Field: "session_id", Message: "may only contain letters, digits, '.', '_' and '-'"},
+		}}
+	}
+
+	if errs := sm.normalizeConfigs(req.Scenarios, nil); len(errs) > 0 {
+		return nil, &scenarios.ValidationError{Fields: errs}
+	}
+
+	sessionId := req.SessionId
+	if sessionId == "" {
+		sessionId = fmt.Sprintf("session-%d", time.Now().UnixNano())
+	}
+
+	steps := newSessionSteps(req.Scenarios)
+	if conflicts := sm.findConflicts(sessionId, steps); len(conflicts) > 0 {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	if previous, ok := sm.sessions[sessionId]; ok {
+		sm.stopSession(previous, StopReasonSessionReplaced)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	session := &ScenarioSession{
-		SessionId:  fmt.Sprintf("session-%d", time.Now().Unix()),
+		SessionId:  sessionId,
 		Scenarios:  []string{},
 		StartTime:  time.Now(),
 		CancelFunc: cancel,
-	}
-
-	details := make([]ScenarioDetail, 0, len(configs))
-	var maxDuration int
+		steps:      steps,
+		labels:     req.Labels,
+		wake:       make(chan struct{}, 1),
 
-	for _, config := range configs {
-		scenario, ok := sm.scenarios[config.Name]
-		if !ok {
-			details = append(details, ScenarioDetail{
-				Name:    config.Name,
-				Success: false,
-				Error:   fmt.Sprintf("scenario %s not found", config.Name),
-			})
-			continue
-		}
+		sampleStride: 1,
+	}
 
-		err := scenario.Start(ctx, config.Params)
-		if err != nil {
-			details = append(details, ScenarioDetail{
-				Name:    config.Name,
-				Success: false,
-				Error:   err.Error(),
-			})
-		} else {
-			session.Scenarios = append(session.Scenarios, config.Name)
-			details = append(details, ScenarioDetail{
-				Name:    config.Name,
-				Success: true,
-			})
-		}
+	sm.events.Publish(Event{Type: EventSessionStarted, SessionId: sessionId})
+	sm.advanceSession(ctx, session)
 
-		if config.Duration > maxDuration {
-			maxDuration = config.Duration
+	seen := make(map[string]bool)
+	for _, step := range session.steps {
+		if step.state != StepFailed && !seen[step.name] {
+			seen[step.name] = true
+			session.Scenarios = append(session.Scenarios, step.name)
 		}
 	}
 
-	if maxDuration > 0 {
-		session.RecoveryTimer = time.AfterFunc(time.Duration(maxDuration)*time.Second, func() {
-			sm.StopAllScenarios()
-		})
-	}
+	sm.sessions[sessionId] = session
+	go sm.runScheduler(ctx, session)
 
-	sm.currentSession = session
-
-	status := "success"
-	for _, detail := range details {
+	resp := session.resp(time.Now())
+	resp.Status = "success"
+	for _, detail := range resp.Details {
 		if !detail.Success {
-			status = "partial"
+			resp.Status = "partial"
 			break
 		}
 	}
 	if len(session.Scenarios) == 0 {
-		status = "failed"
+		resp.Status = "failed"
 	}
 
-	return &CompositeScenarioResp{
-		SessionId: session.SessionId,
-		Scenarios: session.Scenarios,
-		Status:    status,
-		Details:   details,
-	}, nil
+	return resp, nil
 }
 
-func (sm *ScenarioManager) stopCurrentSession() {
-	if sm.currentSession == nil {
-		return
-	}
-
-	if sm.currentSession.RecoveryTimer != nil {
-		sm.currentSession.RecoveryTimer.Stop()
-	}
+// normalizeConfigs validates every timeline entry against the schema of its
+// scenario, then against the scenario itself when it is a Validator, and
+// replaces its params with the normalized values. Callers must hold sm.mu.
+func (sm *ScenarioManager) normalizeConfigs(configs []ScenarioConfig, errs []scenarios.FieldError) []scenarios.FieldError {
+	for i := range configs {
+		config := &configs[i]
 
-	sm.currentSession.CancelFunc()
+		if config.StartAfter < 0 {
+			errs = append(errs, scenarios.FieldError{Scenario: config.Name, Field: "start_after", Message: "must be >= 0"})
+		}
+		if config.Duration < 0 {
+			errs = append(errs, scenarios.FieldError{Scenario: config.Name, Field: "duration", Message: "must be >= 0"})
+		}
+		if len(config.Then) > 0 && config.Duration <= 0 {
+			errs = append(errs, scenarios.FieldError{Scenario: config.Name, Field: "then", Message: "requires a positive duration"})
+		}
 
-	for _, scenarioName := range sm.currentSession.Scenarios {
-		if scenario, ok := sm.scenarios[scenarioName]; ok {
-			scenario.Stop()
+		scenario, ok := sm.scenarios[config.Name]
+		if !ok {
+			errs = append(errs, scenarios.FieldError{Scenario: config.Name, Field: "name", Message: "unknown scenario"})
+		} else {
+			params, fieldErrs := scenario.Schema().Normalize(config.Name, config.Params)
+			if validator, ok := scenario.(scenarios.Validator); ok && len(fieldErrs) == 0 {
+				for _, err := range validator.Validate(params) {
+					err.Scenario = config.Name
+					fieldErrs = append(fieldErrs, err)
+				}
+			}
+			errs = append(errs, fieldErrs...)
+			config.Params = params
 		}
+
+		errs = sm.normalizeConfigs(config.Then, errs)
 	}
 
-	sm.currentSession = nil
+	return errs
 }
 
 func (sm *ScenarioManager) StopAllScenarios() error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	sm.stopCurrentSession()
+	for _, session := range sm.sessions {
+		sm.stopSession(session, StopReasonManual)
+	}
 
 	return nil
 }
 
+// GetCurrentSession reports the most recently started session.
 func (sm *ScenarioManager) GetCurrentSession() *CompositeScenarioResp {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	if sm.currentSession == nil {
+	sessions := sm.sessionsByStart()
+	if len(sessions) == 0 {
 		return &CompositeScenarioResp{
 			Status: "no active session",
 		}
 	}
 
-	details := make([]ScenarioDetail, 0, len(sm.currentSession.Scenarios))
-	for _, name := range sm.currentSession.Scenarios {
-		if scenario, ok := sm.scenarios[name]; ok {
-			status := scenario.Status()
-			details = append(details, ScenarioDetail{
-				Name:    name,
-				Success: status.Running,
-			})
-		}
-	}
-
-	return &CompositeScenarioResp{
-		SessionId: sm.currentSession.SessionId,
-		Scenarios: sm.currentSession.Scenarios,
-		Status:    "running",
-		Details:   details,
-	}
+	return sessions[len(sessions)-1].resp(time.Now())
 }
 
 type ScenarioConfig struct {
-	Name     string                 `json:"name"`
-	Params   map[string]interface{} `json:"params"`
-	Duration int                    `json:"duration,omitempty"`
+	Name       string                 `json:"name"`
+	Params     map[string]interface{} `json:"params,optional"`
+	StartAfter int                    `json:"start_after,optional"`
+	Duration   int                    `json:"duration,optional"`
+	Then       []ScenarioConfig       `json:"then,optional"`
+}
+
+type CompositeScenarioReq struct {
+	SessionId string           `json:"session_id,optional"`
+	Scenarios []ScenarioConfig `json:"scenarios"`
+	Labels    *SessionLabels   `json:"labels,optional"`
 }
 
 type CompositeScenarioResp struct {
@@ -265,7 +359,17 @@ type CompositeScenarioResp struct {
 }
 
 type ScenarioDetail struct {
-	Name    string `json:"name"`
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+	Name             string                 `json:"name"`
+	Success          bool                   `json:"success"`
+	Error            string                 `json:"error,omitempty"`
+	State            string                 `json:"state,omitempty"`
+	StartAfter       int                    `json:"start_after,omitempty"`
+	Duration         int                    `json:"duration,omitempty"`
+	StartsInSeconds  float64                `json:"starts_in_seconds,omitempty"`
+	RemainingSeconds float64                `json:"remaining_seconds,omitempty"`
+	StopReason       string                 `json:"stop_reason,omitempty"`
+	StartedAt        *time.Time             `json:"started_at,omitempty"`
+	StoppedAt        *time.Time             `json:"stopped_at,omitempty"`
+	Params           map[string]interface{} `json:"params,omitempty"`
+	FinalMetrics     map[string]float64     `json:"final_metrics,omitempty"`
 }
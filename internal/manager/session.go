@@ -0,0 +1,622 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/metrics"
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+)
+
+const (
+	StopReasonExpired         = "expired"
+	StopReasonFailed          = "failed"
+	StopReasonManual          = "manual"
+	StopReasonSessionReplaced = "session_replaced"
+	StopReasonSuperseded      = "superseded"
+)
+
+const (
+	StepScheduled = "scheduled"
+	StepRunning   = "running"
+	StepStopped   = "stopped"
+	StepFailed    = "failed"
+	StepCancelled = "cancelled"
+)
+
+type ScenarioSession struct {
+	SessionId  string
+	Scenarios  []string
+	StartTime  time.Time
+	CancelFunc context.CancelFunc
+	steps      []*sessionStep
+	labels     *SessionLabels
+	wake       chan struct{}
+	samples    []MetricSample
+	// sampleStride grows when samples are thinned out so that long sessions
+	// stay within the configured sample budget.
+	sampleStride int
+	sampleTicks  int
+}
+
+var ErrSessionNotFound = errors.New("session not found")
+
+var sessionIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ScenarioConflict names a scenario that is already driven by another session.
+type ScenarioConflict struct {
+	Scenario  string `json:"scenario"`
+	SessionId string `json:"session_id"`
+}
+
+type ConflictError struct {
+	Conflicts []ScenarioConflict
+}
+
+func (e *ConflictError) Error() string {
+	msgs := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		msgs = append(msgs, fmt.Sprintf("scenario %s is already driven by session %s", c.Scenario, c.SessionId))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func validSessionId(id string) bool {
+	return sessionIdPattern.MatchString(id)
+}
+
+// sessionStep is one entry of a session timeline. Its start and expiry are
+// planned relative to the session start so that every step fires at a
+// deterministic offset regardless of scheduling jitter.
+type sessionStep struct {
+	name       string
+	params     map[string]interface{}
+	startAt    time.Duration
+	duration   time.Duration
+	state      string
+	startTime  time.Time
+	stopTime   time.Time
+	stopReason string
+	err        string
+	// finalMetrics is the scenario status captured right before it stopped.
+	finalMetrics map[string]float64
+	// descendants are the steps nested under this one through "then", at
+	// any depth. They move with the planned end of this step.
+	descendants []*sessionStep
+}
+
+// stepEvent is the next thing the scheduler has to do for a step: either
+// start it or expire it.
+type stepEvent struct {
+	step  *sessionStep
+	at    time.Time
+	start bool
+}
+
+func newSessionSteps(configs []ScenarioConfig) []*sessionStep {
+	steps := flattenTimeline(configs, 0, nil)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].startAt < steps[j].startAt
+	})
+	return steps
+}
+
+// flattenTimeline turns nested timeline entries into a flat list of steps.
+// Follow-ups listed in "then" are offset from the planned end of their parent.
+func flattenTimeline(configs []ScenarioConfig, offset time.Duration, steps []*sessionStep) []*sessionStep {
+	for _, config := range configs {
+		step := &sessionStep{
+			name:     config.Name,
+			params:   config.Params,
+			startAt:  offset + time.Duration(config.StartAfter)*time.Second,
+			duration: time.Duration(config.Duration) * time.Second,
+			state:    StepScheduled,
+		}
+		steps = append(steps, step)
+
+		if len(config.Then) > 0 {
+			first := len(steps)
+			steps = flattenTimeline(config.Then, step.startAt+step.duration, steps)
+			step.descendants = append([]*sessionStep(nil), steps[first:]...)
+		}
+	}
+	return steps
+}
+
+func (s *ScenarioSession) nextEvent() (stepEvent, bool) {
+	var next stepEvent
+	found := false
+
+	for _, step := range s.steps {
+		var ev stepEvent
+		switch step.state {
+		case StepScheduled:
+			ev = stepEvent{step: step, at: s.StartTime.Add(step.startAt), start: true}
+		case StepRunning:
+			if step.duration <= 0 {
+				continue
+			}
+			ev = stepEvent{step: step, at: s.StartTime.Add(step.startAt + step.duration)}
+		default:
+			continue
+		}
+
+		// Expirations win ties so that a sequence of steps on the same
+		// scenario hands over cleanly at the boundary.
+		if !found || ev.at.Before(next.at) || (ev.at.Equal(next.at) && next.start && !ev.start) {
+			next = ev
+			found = true
+		}
+	}
+
+	return next, found
+}
+
+// claims reports whether the session has a scheduled or running step for the
+// named scenario.
+func (s *ScenarioSession) claims(name string) bool {
+	for _, step := range s.steps {
+		if step.name == name && (step.state == StepScheduled || step.state == StepRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+// notify wakes the scheduler so that it re-evaluates the timeline.
+func (s *ScenarioSession) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *ScenarioSession) active() bool {
+	for _, step := range s.steps {
+		if step.state == StepScheduled || step.state == StepRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// runScheduler drives the session timeline and samples scenario metrics
+// until every step has ended or the session context is cancelled.
+func (sm *ScenarioManager) runScheduler(ctx context.Context, session *ScenarioSession) {
+	ticker := time.NewTicker(sm.sampleInterval)
+	defer ticker.Stop()
+
+	for {
+		sm.mu.Lock()
+		if ctx.Err() != nil {
+			sm.mu.Unlock()
+			return
+		}
+		sm.advanceSession(ctx, session)
+		if !session.active() {
+			sm.finishSession(session, session.endReason())
+			sm.mu.Unlock()
+			return
+		}
+		ev, ok := session.nextEvent()
+		sm.mu.Unlock()
+
+		var timerC <-chan time.Time
+		var timer *time.Timer
+		if ok {
+			timer = time.NewTimer(time.Until(ev.at))
+			timerC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-session.wake:
+		case <-timerC:
+		case <-ticker.C:
+			sm.mu.Lock()
+			session.sampleTicks++
+			if ctx.Err() == nil && session.sampleTicks%session.sampleStride == 0 {
+				sm.sampleSession(session)
+			}
+			sm.mu.Unlock()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// advanceSession fires every event that is due. Callers must hold sm.mu.
+func (sm *ScenarioManager) advanceSession(ctx context.Context, session *ScenarioSession) {
+	for {
+		ev, ok := session.nextEvent()
+		if !ok || ev.at.After(time.Now()) {
+			return
+		}
+
+		if ev.start {
+			sm.startStep(ctx, session, ev.step)
+		} else {
+			sm.stopStep(session, ev.step, StopReasonExpired)
+		}
+	}
+}
+
+func (sm *ScenarioManager) startStep(ctx context.Context, session *ScenarioSession, step *sessionStep) {
+	scenario, ok := sm.scenarios[step.name]
+	if !ok {
+		step.state = StepFailed
+		step.err = "scenario " + step.name + " not found"
+		sm.publishStep(EventScenarioFailed, session, step)
+		return
+	}
+
+	var previous *sessionStep
+	for _, other := range session.steps {
+		if other != step && other.name == step.name && other.state == StepRunning {
+			sm.stopStep(session, other, StopReasonSuperseded)
+			previous = other
+		}
+	}
+
+	step.startTime = time.Now()
+	if err := scenario.Start(ctx, step.params); err != nil {
+		step.state = StepFailed
+		step.err = err.Error()
+		sm.publishStep(EventScenarioFailed, session, step)
+		return
+	}
+	step.state = StepRunning
+	metrics.ScenarioStarts.Inc(step.name)
+	sm.publishStep(EventScenarioStarted, session, step)
+
+	if previous != nil && !reflect.DeepEqual(previous.params, step.params) {
+		sm.events.Publish(Event{
+			Type:           EventScenarioParamsChanged,
+			SessionId:      session.SessionId,
+			Scenario:       step.name,
+			Params:         step.params,
+			PreviousParams: previous.params,
+		})
+	}
+}
+
+func (sm *ScenarioManager) stopStep(session *ScenarioSession, step *sessionStep, reason string) {
+	switch step.state {
+	case StepRunning:
+		if scenario, ok := sm.scenarios[step.name]; ok {
+			step.finalMetrics = copyMetrics(scenario.Status().Metrics)
+			scenario.Stop()
+		}
+		step.state = StepStopped
+	case StepScheduled:
+		step.state = StepCancelled
+	default:
+		return
+	}
+
+	step.stopTime = time.Now()
+	step.stopReason = reason
+	if step.state == StepStopped {
+		metrics.ScenarioStops.Inc(step.name, reason)
+		sm.publishStep(EventScenarioStopped, session, step)
+	}
+}
+
+// findConflicts lists scenarios in steps that another session still drives.
+// Callers must hold sm.mu.
+func (sm *ScenarioManager) findConflicts(sessionId string, steps []*sessionStep) []ScenarioConflict {
+	var conflicts []ScenarioConflict
+	seen := make(map[string]bool)
+
+	for _, step := range steps {
+		if seen[step.name] {
+			continue
+		}
+		seen[step.name] = true
+
+		for id, session := range sm.sessions {
+			if id != sessionId && session.claims(step.name) {
+				conflicts = append(conflicts, ScenarioConflict{Scenario: step.name, SessionId: id})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// stopSession cancels pending steps, stops running ones and moves the
+// session to the history. Callers must hold sm.mu.
+func (sm *ScenarioManager) stopSession(session *ScenarioSession, reason string) {
+	session.CancelFunc()
+	sm.sampleSession(session)
+	for _, step := range session.steps {
+		sm.stopStep(session, step, reason)
+	}
+
+	sm.finishSession(session, reason)
+}
+
+// endReason tells why a session whose steps have all ended is over: manual
+// when a step was stopped by hand, failed when no step ever ran and empty
+// when the timeline completed.
+func (s *ScenarioSession) endReason() string {
+	ran := false
+	for _, step := range s.steps {
+		if step.stopReason == StopReasonManual {
+			return StopReasonManual
+		}
+		if step.state == StepStopped {
+			ran = true
+		}
+	}
+	if !ran {
+		return StopReasonFailed
+	}
+	return ""
+}
+
+// finishSession archives a session that has ended. An empty reason means the
+// session completed on its own. Callers must hold sm.mu.
+func (sm *ScenarioManager) finishSession(session *ScenarioSession, reason string) {
+	session.CancelFunc()
+	if sm.sessions[session.SessionId] == session {
+		delete(sm.sessions, session.SessionId)
+	}
+
+	report := session.report(time.Now())
+	endTime := time.Now()
+	report.EndTime = &endTime
+	report.StopReason = reason
+	if reason == "" {
+		report.Status = "completed"
+	} else {
+		report.Status = "stopped"
+	}
+	sm.publishSession(session, report.Status, reason)
+
+	sm.history.add(report)
+}
+
+// sampleSession records the metrics of every running step. When the sample
+// budget is exhausted every other sample is dropped and the stride doubles.
+// Callers must hold sm.mu.
+func (sm *ScenarioManager) sampleSession(session *ScenarioSession) {
+	metrics := make(map[string]map[string]float64)
+	for _, step := range session.steps {
+		if step.state != StepRunning {
+			continue
+		}
+		if scenario, ok := sm.scenarios[step.name]; ok {
+			metrics[step.name] = copyMetrics(scenario.Status().Metrics)
+		}
+	}
+	if len(metrics) == 0 {
+		return
+	}
+
+	session.samples = append(session.samples, MetricSample{
+		Time:    time.Now(),
+		Metrics: metrics,
+	})
+
+	if sm.maxSamples > 0 && len(session.samples) > sm.maxSamples {
+		thinned := session.samples[:0]
+		for i, sample := range session.samples {
+			if i%2 == 0 {
+				thinned = append(thinned, sample)
+			}
+		}
+		session.samples = thinned
+		session.sampleStride *= 2
+	}
+}
+
+func copyMetrics(metrics map[string]float64) map[string]float64 {
+	copied := make(map[string]float64, len(metrics))
+	for k, v := range metrics {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (sm *ScenarioManager) ListSessions() []*CompositeScenarioResp {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	now := time.Now()
+	list := make([]*CompositeScenarioResp, 0, len(sm.sessions))
+	for _, session := range sm.sessionsByStart() {
+		list = append(list, session.resp(now))
+	}
+
+	return list
+}
+
+// GetSession reports a live session, falling back to the history for
+// sessions that have already ended.
+func (sm *ScenarioManager) GetSession(id string) (*CompositeScenarioResp, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if session, ok := sm.sessions[id]; ok {
+		return session.resp(time.Now()), nil
+	}
+
+	report, ok := sm.history.get(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return &CompositeScenarioResp{
+		SessionId: report.SessionId,
+		Scenarios: report.Scenarios,
+		Status:    report.Status,
+		Details:   report.Details,
+	}, nil
+}
+
+func (sm *ScenarioManager) GetSessionReport(id string) (*SessionReport, error) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	if session, ok := sm.sessions[id]; ok {
+		return session.report(time.Now()), nil
+	}
+
+	report, ok := sm.history.get(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return report, nil
+}
+
+func (sm *ScenarioManager) SessionHistory() []SessionSummary {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	return sm.history.list()
+}
+
+func (sm *ScenarioManager) StopSession(id string) (*CompositeScenarioResp, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	sm.stopSession(session, StopReasonManual)
+
+	resp := session.resp(time.Now())
+	resp.Status = "stopped"
+	return resp, nil
+}
+
+// ExtendSession pushes back the expiry of every running step that has a
+// duration. Steps that have not started yet keep their planned offsets.
+func (sm *ScenarioManager) ExtendSession(id string, seconds int) (*CompositeScenarioResp, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	if seconds <= 0 {
+		return nil, &scenarios.ValidationError{Fields: []scenarios.FieldError{
+			{Field: "seconds", Message: "must be > 0"},
+		}}
+	}
+
+	extended := false
+	for _, step := range session.steps {
+		if step.state == StepRunning && step.duration > 0 {
+			step.duration += time.Duration(seconds) * time.Second
+			for _, descendant := range step.descendants {
+				descendant.startAt += time.Duration(seconds) * time.Second
+			}
+			extended = true
+		}
+	}
+	if !extended {
+		return nil, fmt.Errorf("session %s has no running scenario with a duration", id)
+	}
+	sort.SliceStable(session.steps, func(i, j int) bool {
+		return session.steps[i].startAt < session.steps[j].startAt
+	})
+
+	session.notify()
+
+	return session.resp(time.Now()), nil
+}
+
+func (sm *ScenarioManager) sessionsByStart() []*ScenarioSession {
+	list := make([]*ScenarioSession, 0, len(sm.sessions))
+	for _, session := range sm.sessions {
+		list = append(list, session)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime)
+	})
+	return list
+}
+
+func (s *ScenarioSession) resp(now time.Time) *CompositeScenarioResp {
+	details := make([]ScenarioDetail, 0, len(s.steps))
+	for _, step := range s.steps {
+		details = append(details, step.detail(s, now))
+	}
+
+	status := "completed"
+	if s.active() {
+		status = "running"
+	}
+
+	return &CompositeScenarioResp{
+		SessionId: s.SessionId,
+		Scenarios: s.Scenarios,
+		Status:    status,
+		Details:   details,
+	}
+}
+
+func (s *ScenarioSession) report(now time.Time) *SessionReport {
+	resp := s.resp(now)
+	samples := make([]MetricSample, len(s.samples))
+	copy(samples, s.samples)
+
+	return &SessionReport{
+		SessionId: s.SessionId,
+		Status:    resp.Status,
+		StartTime: s.StartTime,
+		Scenarios: s.Scenarios,
+		Labels:    s.labels,
+		Details:   resp.Details,
+		Samples:   samples,
+	}
+}
+
+func (step *sessionStep) detail(session *ScenarioSession, now time.Time) ScenarioDetail {
+	detail := ScenarioDetail{
+		Name:         step.name,
+		Success:      step.err == "" && step.stopReason != StopReasonFailed,
+		Error:        step.err,
+		StartAfter:   int(step.startAt / time.Second),
+		Duration:     int(step.duration / time.Second),
+		State:        step.state,
+		StopReason:   step.stopReason,
+		Params:       step.params,
+		FinalMetrics: step.finalMetrics,
+	}
+
+	if !step.startTime.IsZero() {
+		startedAt := step.startTime
+		detail.StartedAt = &startedAt
+	}
+
+	switch step.state {
+	case StepScheduled:
+		detail.StartsInSeconds = session.StartTime.Add(step.startAt).Sub(now).Round(time.Millisecond).Seconds()
+	case StepRunning:
+		if step.duration > 0 {
+			end := session.StartTime.Add(step.startAt + step.duration)
+			detail.RemainingSeconds = end.Sub(now).Round(time.Millisecond).Seconds()
+		}
+	case StepStopped, StepCancelled:
+		stoppedAt := step.stopTime
+		detail.StoppedAt = &stoppedAt
+	}
+
+	return detail
+}
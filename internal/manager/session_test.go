@@ -0,0 +1,410 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/scenarios"
+)
+
+type fakeScenario struct {
+	name     string
+	startErr error
+
+	mu      sync.Mutex
+	running bool
+	params  map[string]interface{}
+}
+
+func (f *fakeScenario) Name() string     { return f.name }
+func (f *fakeScenario) Describe() string { return "fake " + f.name }
+
+func (f *fakeScenario) Start(ctx context.Context, params map[string]interface{}) error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = true
+	f.params = params
+	return nil
+}
+
+func (f *fakeScenario) Stop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = false
+	return nil
+}
+
+func (f *fakeScenario) Status() scenarios.ScenarioStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return scenarios.ScenarioStatus{Running: f.running, Params: f.params, Metrics: map[string]float64{"level": 1}}
+}
+
+func (f *fakeScenario) Schema() scenarios.ParamSchema {
+	return scenarios.ParamSchema{{Name: "level", Type: scenarios.ParamInt, Default: 1}}
+}
+
+func (f *fakeScenario) Diagnosis(params map[string]interface{}) scenarios.Diagnosis {
+	return scenarios.Diagnosis{Category: f.name}
+}
+
+// newTestManager returns a manager driving fake scenarios, with a broken one
+// that never starts.
+func newTestManager(t *testing.T) *ScenarioManager {
+	sm := &ScenarioManager{
+		scenarios:      make(map[string]scenarios.Scenario),
+		sessions:       make(map[string]*ScenarioSession),
+		history:        newSessionHistory(10, ""),
+		events:         NewEventBus(),
+		sampleInterval: time.Hour,
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		sm.Register(&fakeScenario{name: name})
+	}
+	sm.Register(&fakeScenario{name: "broken", startErr: errors.New("cannot start")})
+	t.Cleanup(func() { sm.StopAllScenarios() })
+	return sm
+}
+
+// newTestSession returns a session that started elapsed ago and has no
+// scheduler, so that tests drive it with advanceSession.
+func newTestSession(configs []ScenarioConfig, elapsed time.Duration) *ScenarioSession {
+	return &ScenarioSession{
+		SessionId:    "test",
+		StartTime:    time.Now().Add(-elapsed),
+		CancelFunc:   func() {},
+		steps:        newSessionSteps(configs),
+		wake:         make(chan struct{}, 1),
+		sampleStride: 1,
+	}
+}
+
+func stepStates(session *ScenarioSession) []string {
+	states := make([]string, 0, len(session.steps))
+	for _, step := range session.steps {
+		states = append(states, step.name+":"+step.state)
+	}
+	return states
+}
+
+func TestAdvanceSessionRecoversStepsIndependently(t *testing.T) {
+	configs := []ScenarioConfig{
+		{Name: "a", Duration: 10},
+		{Name: "b", Duration: 30},
+		{Name: "c"},
+		{Name: "broken", Duration: 10},
+	}
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    []string
+	}{
+		{name: "just started", elapsed: 0, want: []string{"a:running", "b:running", "c:running", "broken:failed"}},
+		{name: "first expired", elapsed: 15 * time.Second, want: []string{"a:stopped", "b:running", "c:running", "broken:failed"}},
+		{name: "both expired", elapsed: 40 * time.Second, want: []string{"a:stopped", "b:stopped", "c:running", "broken:failed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestManager(t)
+			session := newTestSession(configs, tt.elapsed)
+
+			sm.mu.Lock()
+			sm.advanceSession(context.Background(), session)
+			sm.mu.Unlock()
+
+			if got := stepStates(session); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("states = %v, want %v", got, tt.want)
+			}
+			for _, step := range session.steps {
+				if step.state == StepStopped && step.stopReason != StopReasonExpired {
+					t.Errorf("%s stop reason = %q, want %q", step.name, step.stopReason, StopReasonExpired)
+				}
+				running := sm.scenarios[step.name].Status().Running
+				if running != (step.state == StepRunning) {
+					t.Errorf("%s running = %v in state %s", step.name, running, step.state)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSessionSteps(t *testing.T) {
+	steps := newSessionSteps([]ScenarioConfig{
+		{Name: "a", StartAfter: 5, Duration: 10, Then: []ScenarioConfig{
+			{Name: "b", Duration: 20, Then: []ScenarioConfig{
+				{Name: "c", StartAfter: 2},
+			}},
+		}},
+		{Name: "d", StartAfter: 1},
+	})
+
+	want := []struct {
+		name        string
+		startAt     time.Duration
+		descendants int
+	}{
+		{name: "d", startAt: time.Second},
+		{name: "a", startAt: 5 * time.Second, descendants: 2},
+		{name: "b", startAt: 15 * time.Second, descendants: 1},
+		{name: "c", startAt: 37 * time.Second},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(steps), len(want))
+	}
+	for i, w := range want {
+		step := steps[i]
+		if step.name != w.name || step.startAt != w.startAt || len(step.descendants) != w.descendants {
+			t.Errorf("step %d = %s at %v with %d descendants, want %s at %v with %d",
+				i, step.name, step.startAt, len(step.descendants), w.name, w.startAt, w.descendants)
+		}
+	}
+}
+
+func TestAdvanceSessionTimeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []ScenarioConfig
+		elapsed time.Duration
+		want    []string
+		reasons []string
+	}{
+		{
+			name: "waiting for offsets",
+			configs: []ScenarioConfig{
+				{Name: "a", Duration: 10, Then: []ScenarioConfig{{Name: "a", Duration: 10}}},
+				{Name: "b", StartAfter: 20},
+			},
+			elapsed: 5 * time.Second,
+			want:    []string{"a:running", "a:scheduled", "b:scheduled"},
+			reasons: []string{"", "", ""},
+		},
+		{
+			name: "follow-up takes over at the boundary",
+			configs: []ScenarioConfig{
+				{Name: "a", Duration: 10, Then: []ScenarioConfig{{Name: "a", Duration: 10}}},
+				{Name: "b", StartAfter: 20},
+			},
+			elapsed: 12 * time.Second,
+			want:    []string{"a:stopped", "a:running", "b:scheduled"},
+			reasons: []string{StopReasonExpired, "", ""},
+		},
+		{
+			name: "whole timeline",
+			configs: []ScenarioConfig{
+				{Name: "a", Duration: 10, Then: []ScenarioConfig{{Name: "a", Duration: 10}}},
+				{Name: "b", StartAfter: 20},
+			},
+			elapsed: 25 * time.Second,
+			want:    []string{"a:stopped", "a:stopped", "b:running"},
+			reasons: []string{StopReasonExpired, StopReasonExpired, ""},
+		},
+		{
+			name: "later step supersedes a running one",
+			configs: []ScenarioConfig{
+				{Name: "a"},
+				{Name: "a", StartAfter: 5},
+			},
+			elapsed: 6 * time.Second,
+			want:    []string{"a:stopped", "a:running"},
+			reasons: []string{StopReasonSuperseded, ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestManager(t)
+			session := newTestSession(tt.configs, tt.elapsed)
+
+			sm.mu.Lock()
+			sm.advanceSession(context.Background(), session)
+			sm.mu.Unlock()
+
+			if got := stepStates(session); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("states = %v, want %v", got, tt.want)
+			}
+			for i, step := range session.steps {
+				if step.stopReason != tt.reasons[i] {
+					t.Errorf("step %d stop reason = %q, want %q", i, step.stopReason, tt.reasons[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeConfigsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []ScenarioConfig
+		want    []string
+	}{
+		{name: "valid", configs: []ScenarioConfig{{Name: "a", Duration: 5, Then: []ScenarioConfig{{Name: "b"}}}}},
+		{name: "negative offsets", configs: []ScenarioConfig{{Name: "a", StartAfter: -1, Duration: -1}}, want: []string{"a.start_after", "a.duration"}},
+		{name: "then without duration", configs: []ScenarioConfig{{Name: "a", Then: []ScenarioConfig{{Name: "b"}}}}, want: []string{"a.then"}},
+		{name: "unknown scenario in then", configs: []ScenarioConfig{{Name: "a", Duration: 5, Then: []ScenarioConfig{{Name: "nope"}}}}, want: []string{"nope.name"}},
+		{name: "invalid param", configs: []ScenarioConfig{{Name: "a", Params: map[string]interface{}{"level": "high"}}}, want: []string{"a.level"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestManager(t)
+			var got []string
+			for _, err := range sm.normalizeConfigs(tt.configs, nil) {
+				got = append(got, err.Scenario+"."+err.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartCompositeConflicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       CompositeScenarioReq
+		conflicts []ScenarioConflict
+	}{
+		{
+			name:      "running scenario of another session",
+			req:       CompositeScenarioReq{SessionId: "s2", Scenarios: []ScenarioConfig{{Name: "a"}}},
+			conflicts: []ScenarioConflict{{Scenario: "a", SessionId: "s1"}},
+		},
+		{
+			name:      "scheduled scenario of another session",
+			req:       CompositeScenarioReq{SessionId: "s2", Scenarios: []ScenarioConfig{{Name: "c"}, {Name: "b"}}},
+			conflicts: []ScenarioConflict{{Scenario: "b", SessionId: "s1"}},
+		},
+		{
+			name: "free scenario",
+			req:  CompositeScenarioReq{SessionId: "s2", Scenarios: []ScenarioConfig{{Name: "c"}}},
+		},
+		{
+			name: "same session is replaced",
+			req:  CompositeScenarioReq{SessionId: "s1", Scenarios: []ScenarioConfig{{Name: "a"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestManager(t)
+			_, err := sm.StartComposite(CompositeScenarioReq{
+				SessionId: "s1",
+				Scenarios: []ScenarioConfig{{Name: "a"}, {Name: "b", StartAfter: 60}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = sm.StartComposite(tt.req)
+			var conflictErr *ConflictError
+			if errors.As(err, &conflictErr) {
+				if !reflect.DeepEqual(conflictErr.Conflicts, tt.conflicts) {
+					t.Errorf("conflicts = %v, want %v", conflictErr.Conflicts, tt.conflicts)
+				}
+				return
+			}
+			if err != nil || tt.conflicts != nil {
+				t.Fatalf("StartComposite error = %v, want conflicts %v", err, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestStartCompositeReplacesSession(t *testing.T) {
+	sm := newTestManager(t)
+	for i := 0; i < 2; i++ {
+		if _, err := sm.StartComposite(CompositeScenarioReq{SessionId: "s1", Scenarios: []ScenarioConfig{{Name: "a"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, ok := sm.history.get("s1")
+	if !ok {
+		t.Fatal("replaced session missing from the history")
+	}
+	if report.StopReason != StopReasonSessionReplaced {
+		t.Errorf("stop reason = %q, want %q", report.StopReason, StopReasonSessionReplaced)
+	}
+	if len(sm.sessions) != 1 || !sm.scenarios["a"].Status().Running {
+		t.Errorf("want one session still driving a, got %d sessions", len(sm.sessions))
+	}
+}
+
+func TestExtendSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		configs  []ScenarioConfig
+		id       string
+		seconds  int
+		wantErr  bool
+		startAts map[string]time.Duration
+		duration time.Duration
+	}{
+		{
+			name: "shifts follow-ups at any depth",
+			configs: []ScenarioConfig{
+				{Name: "a", Duration: 10, Then: []ScenarioConfig{
+					{Name: "b", Duration: 5, Then: []ScenarioConfig{{Name: "c"}}},
+				}},
+			},
+			id:       "s1",
+			seconds:  30,
+			startAts: map[string]time.Duration{"a": 0, "b": 40 * time.Second, "c": 45 * time.Second},
+			duration: 40 * time.Second,
+		},
+		{
+			name: "leaves other scheduled steps alone",
+			configs: []ScenarioConfig{
+				{Name: "a", Duration: 10},
+				{Name: "b", StartAfter: 20},
+			},
+			id:       "s1",
+			seconds:  30,
+			startAts: map[string]time.Duration{"a": 0, "b": 20 * time.Second},
+			duration: 40 * time.Second,
+		},
+		{name: "unknown session", configs: []ScenarioConfig{{Name: "a", Duration: 10}}, id: "s2", seconds: 30, wantErr: true},
+		{name: "non positive seconds", configs: []ScenarioConfig{{Name: "a", Duration: 10}}, id: "s1", seconds: 0, wantErr: true},
+		{name: "nothing to extend", configs: []ScenarioConfig{{Name: "a"}}, id: "s1", seconds: 30, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := newTestManager(t)
+			if _, err := sm.StartComposite(CompositeScenarioReq{SessionId: "s1", Scenarios: tt.configs}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := sm.ExtendSession(tt.id, tt.seconds)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ExtendSession succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtendSession error: %v", err)
+			}
+
+			sm.mu.RLock()
+			defer sm.mu.RUnlock()
+			for _, step := range sm.sessions["s1"].steps {
+				if step.startAt != tt.startAts[step.name] {
+					t.Errorf("%s starts at %v, want %v", step.name, step.startAt, tt.startAts[step.name])
+				}
+				if step.name == "a" && step.duration != tt.duration {
+					t.Errorf("a lasts %v, want %v", step.duration, tt.duration)
+				}
+			}
+		})
+	}
+}
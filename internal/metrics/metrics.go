@@ -0,0 +1,103 @@
+package metrics
+
+import "github.com/zeromicro/go-zero/core/metric"
+
+const namespace = "mockserver"
+
+var (
+	ScenarioActive = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: namespace,
+		Subsystem: "scenario",
+		Name:      "active",
+		Help:      "Whether a fault scenario is currently injected (1) or not (0).",
+		Labels:    []string{"scenario"},
+	})
+
+	ScenarioValue = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: namespace,
+		Subsystem: "scenario",
+		Name:      "value",
+		Help:      "Current value of a scenario status metric such as target_percent, current_mb or latency_ms.",
+		Labels:    []string{"scenario", "metric"},
+	})
+
+	ScenarioSessionStart = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: namespace,
+		Subsystem: "scenario",
+		Name:      "session_start_time_seconds",
+		Help:      "Unix start time of the session driving the scenario, 0 when idle.",
+		Labels:    []string{"scenario"},
+	})
+
+	ScenarioStarts = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "scenario",
+		Name:      "starts_total",
+		Help:      "Number of times a scenario was started.",
+		Labels:    []string{"scenario"},
+	})
+
+	ScenarioStops = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "scenario",
+		Name:      "stops_total",
+		Help:      "Number of times a scenario was stopped, by stop reason.",
+		Labels:    []string{"scenario", "reason"},
+	})
+
+	SessionsActive = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: namespace,
+		Name:      "sessions_active",
+		Help:      "Number of live sessions.",
+		Labels:    []string{},
+	})
+
+	HealthFailures = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "health",
+		Name:      "failures_served_total",
+		Help:      "Number of failing health check responses served, by probe and status code.",
+		Labels:    []string{"probe", "code"},
+	})
+
+	DependencyErrors = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "dependency",
+		Name:      "errors_served_total",
+		Help:      "Number of faulty mock dependency responses served, by failure type.",
+		Labels:    []string{"failure_type"},
+	})
+
+	HTTPErrors = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "errors_served_total",
+		Help:      "Number of injected HTTP error responses served, by status code.",
+		Labels:    []string{"code"},
+	})
+
+	ConnectionFaults = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "connection",
+		Name:      "faults_total",
+		Help:      "Number of connections broken on purpose, by fault mode.",
+		Labels:    []string{"mode"},
+	})
+
+	DelaysAbandoned = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "delay",
+		Name:      "abandoned_total",
+		Help:      "Number of injected delays cut short, by source and by whether the client cancelled or the server timed out.",
+		Labels:    []string{"source", "reason"},
+	})
+
+	InjectedLatency = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: namespace,
+		Subsystem: "latency",
+		Name:      "injected_ms",
+		Help:      "Latency injected into HTTP requests, in milliseconds.",
+		Labels:    []string{},
+		Buckets:   []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+	})
+)
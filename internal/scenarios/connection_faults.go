@@ -0,0 +1,218 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ConnReset        = "reset"
+	ConnCloseHeaders = "close_headers"
+	ConnTruncate     = "truncate"
+	ConnHang         = "hang"
+)
+
+// ConnectionFault tells the handler how to break the connection of a
+// matched request. Stopped is closed when the scenario stops, so that hung
+// connections are released.
+type ConnectionFault struct {
+	Mode            string
+	BodyBytes       int
+	TruncatePercent float64
+	Hang            time.Duration
+	Stopped         <-chan struct{}
+}
+
+type ConnectionFaults struct {
+	mode            string
+	bodyBytes       int
+	truncatePercent float64
+	hang            time.Duration
+	rng             *rand.Rand
+	matcher         *RequestMatcher
+	injected        atomic.Int64
+	hanging         atomic.Int64
+	stopCh          chan struct{}
+	running         atomic.Bool
+	startTime       time.Time
+	params          map[string]interface{}
+	mu              sync.RWMutex
+	ctx             context.Context
+	cancel          context.CancelFunc
+}
+
+func NewConnectionFaults() *ConnectionFaults {
+	return &ConnectionFaults{
+		stopCh: make(chan struct{}),
+		params: make(map[string]interface{}),
+	}
+}
+
+func (c *ConnectionFaults) Name() string {
+	return "connection_faults"
+}
+
+func (c *ConnectionFaults) Describe() string {
+	return "Breaks the TCP connection of HTTP requests instead of answering them"
+}
+
+func (c *ConnectionFaults) Schema() ParamSchema {
+	return append(ParamSchema{
+		{Name: "mode", Type: ParamString, Default: ConnReset, Enum: []string{ConnReset, ConnCloseHeaders, ConnTruncate, ConnHang}, Description: "How the connection breaks: TCP RST, close in the middle of the headers, truncated body or hang after the headers"},
+		{Name: "body_bytes", Type: ParamInt, Default: 1024, Min: bound(1), Description: "close_headers, truncate and hang only: Content-Length announced in the headers"},
+		{Name: "truncate_percent", Type: ParamFloat, Default: 50, Min: bound(0), Max: bound(99), Description: "truncate only: percentage of the announced body sent before the connection closes"},
+		{Name: "hang_ms", Type: ParamInt, Default: 0, Min: bound(0), Description: "hang only: how long to hang before closing, 0 until the client or the server timeout gives up"},
+		{Name: "seed", Type: ParamInt, Default: 0, Description: "Seed for a reproducible percentage sampling, 0 for a random one"},
+	}, matchParams()...)
+}
+
+func (c *ConnectionFaults) Validate(params map[string]interface{}) []FieldError {
+	return validateMatch(params)
+}
+
+func (c *ConnectionFaults) Diagnosis(params map[string]interface{}) Diagnosis {
+	var symptoms []string
+	switch params["mode"] {
+	case ConnCloseHeaders:
+		symptoms = []string{"clients and proxies get malformed or empty responses (upstream prematurely closed connection, EOF while reading headers)"}
+	case ConnTruncate:
+		symptoms = []string{
+			fmt.Sprintf("response bodies cut short of their Content-Length of %s bytes (unexpected EOF)", paramString(params, "body_bytes")),
+			"decoding errors in clients reading the truncated bodies",
+		}
+	case ConnHang:
+		symptoms = []string{
+			"responses start with headers and then stall until the client or proxy times out",
+			"open connections pile up while CPU and latency of other requests stay normal",
+		}
+	default:
+		symptoms = []string{"connections reset by peer, proxies report upstream connect error or disconnect/reset before headers"}
+	}
+	symptoms = append(symptoms, "proxy 502/503 responses while the service itself logs no errors")
+	if scope := matchScope(params); scope != "" {
+		symptoms = append(symptoms, "only affects "+scope+", other requests succeed")
+	}
+
+	return Diagnosis{
+		Category:  "connection_faults",
+		RootCause: "service breaking TCP connections instead of sending complete HTTP responses",
+		Component: "network",
+		Symptoms:  symptoms,
+	}
+}
+
+func (c *ConnectionFaults) Start(ctx context.Context, params map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	matcher, err := parseRequestMatcher(params)
+	if err != nil {
+		return err
+	}
+
+	if c.running.Load() {
+		c.stop()
+	}
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+	c.startTime = time.Now()
+	c.params = params
+
+	c.mode = ConnReset
+	if mode, ok := params["mode"].(string); ok {
+		c.mode = mode
+	}
+	c.bodyBytes = 1024
+	if v, ok := toFloat(params["body_bytes"]); ok {
+		c.bodyBytes = int(v)
+	}
+	c.truncatePercent = 50
+	if v, ok := toFloat(params["truncate_percent"]); ok {
+		c.truncatePercent = v
+	}
+	c.hang = 0
+	if v, ok := toFloat(params["hang_ms"]); ok {
+		c.hang = time.Duration(v) * time.Millisecond
+	}
+
+	seed := time.Now().UnixNano()
+	if v, ok := toFloat(params["seed"]); ok && v != 0 {
+		seed = int64(v)
+	}
+	c.rng = rand.New(rand.NewSource(seed))
+	c.matcher = matcher
+	c.injected.Store(0)
+
+	c.running.Store(true)
+
+	return nil
+}
+
+func (c *ConnectionFaults) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop()
+}
+
+func (c *ConnectionFaults) stop() error {
+	if !c.running.Load() {
+		return nil
+	}
+
+	c.running.Store(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	close(c.stopCh)
+	c.stopCh = make(chan struct{})
+
+	return nil
+}
+
+func (c *ConnectionFaults) Status() ScenarioStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return ScenarioStatus{
+		Running:   c.running.Load(),
+		StartTime: c.startTime,
+		Params:    c.params,
+		Metrics: map[string]float64{
+			"faults_injected":     float64(c.injected.Load()),
+			"hanging_connections": float64(c.hanging.Load()),
+		},
+	}
+}
+
+// GetFault returns the fault to inflict on r, or nil when r does not match.
+func (c *ConnectionFaults) GetFault(r *http.Request) *ConnectionFault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running.Load() || !c.matcher.Match(r, c.rng) {
+		return nil
+	}
+	c.injected.Add(1)
+
+	return &ConnectionFault{
+		Mode:            c.mode,
+		BodyBytes:       c.bodyBytes,
+		TruncatePercent: c.truncatePercent,
+		Hang:            c.hang,
+		Stopped:         c.stopCh,
+	}
+}
+
+// TrackHang counts a connection hanging in hang mode until the returned
+// function is called.
+func (c *ConnectionFaults) TrackHang() func() {
+	c.hanging.Add(1)
+	return func() {
+		c.hanging.Add(-1)
+	}
+}
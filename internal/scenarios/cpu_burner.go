@@ -2,14 +2,32 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Z3Labs/MockServer/internal/system"
+)
+
+const (
+	// burnPeriod is the length of one busy/idle cycle of a burning goroutine.
+	burnPeriod = 100 * time.Millisecond
+	// controlInterval is how often measured CPU is compared to the target.
+	controlInterval = 500 * time.Millisecond
+	// controlGain scales how much of the error is corrected per interval.
+	controlGain = 0.6
 )
 
 type CPUBurner struct {
-	targetPercent int
+	limits        system.Limits
+	targetPercent NumericParam
+	ofLimit       *NumericParam
+	cores         int
+	duty          float64
+	measured      float64
 	stopCh        chan struct{}
 	running       atomic.Bool
 	startTime     time.Time
@@ -19,8 +37,9 @@ type CPUBurner struct {
 	cancel        context.CancelFunc
 }
 
-func NewCPUBurner() *CPUBurner {
+func NewCPUBurner(limits system.Limits) *CPUBurner {
 	return &CPUBurner{
+		limits: limits,
 		stopCh: make(chan struct{}),
 		params: make(map[string]interface{}),
 	}
@@ -34,10 +53,60 @@ func (c *CPUBurner) Describe() string {
 	return "Increases CPU usage to specified percentage"
 }
 
+func (c *CPUBurner) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "target_percent", Type: ParamInt, Default: 50, Min: bound(0), Max: bound(100), Rampable: true, Description: "CPU usage percentage to burn on every burned core"},
+		{Name: "target_percent_of_limit", Type: ParamFloat, Min: bound(0), Max: bound(100), Rampable: true, Description: "CPU usage as a percentage of the container CPU limit, overrides target_percent"},
+		{Name: "cores", Type: ParamInt, Default: 0, Min: bound(0), Description: "Number of cores to burn, capped at the cores the CPU limit allows, 0 for all of them"},
+	}
+}
+
+func (c *CPUBurner) Diagnosis(params map[string]interface{}) Diagnosis {
+	cores := paramString(params, "cores")
+	if cores == "0" || cores == "?" {
+		cores = "every"
+	}
+
+	usage := fmt.Sprintf("process CPU usage around %s%% of %s core(s)", paramString(params, "target_percent"), cores)
+	if _, ok := params["target_percent_of_limit"]; ok {
+		usage = fmt.Sprintf("process CPU usage around %s%% of the container CPU limit", paramString(params, "target_percent_of_limit"))
+	}
+
+	return Diagnosis{
+		Category:  "cpu_saturation",
+		RootCause: "CPU exhaustion caused by a busy loop in the service",
+		Component: "cpu",
+		Symptoms: []string{
+			usage,
+			"increased request latency",
+			"CPU throttling when running under a container limit",
+		},
+	}
+}
+
 func (c *CPUBurner) Start(ctx context.Context, params map[string]interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	targetPercent, err := parseNumericParam(params, "target_percent", 50)
+	if err != nil {
+		return err
+	}
+
+	var ofLimit *NumericParam
+	if _, ok := params["target_percent_of_limit"]; ok {
+		p, err := parseNumericParam(params, "target_percent_of_limit", 0)
+		if err != nil {
+			return err
+		}
+		ofLimit = &p
+	}
+
+	cores := c.limits.Cores()
+	if n, ok := toFloat(params["cores"]); ok && n >= 1 {
+		cores = int(math.Min(n, float64(c.limits.Cores())))
+	}
+
 	if c.running.Load() {
 		c.stop()
 	}
@@ -45,42 +114,94 @@ func (c *CPUBurner) Start(ctx context.Context, params map[string]interface{}) er
 	c.ctx, c.cancel = context.WithCancel(ctx)
 	c.startTime = time.Now()
 	c.params = params
-
-	targetPercent := 50
-	if tp, ok := params["target_percent"].(float64); ok {
-		targetPercent = int(tp)
-	} else if tp, ok := params["target_percent"].(int); ok {
-		targetPercent = tp
-	}
 	c.targetPercent = targetPercent
+	c.ofLimit = ofLimit
+	c.cores = cores
+	c.duty = c.target() / 100
+	c.measured = 0
 
-	numCores := runtime.NumCPU()
 	c.running.Store(true)
 
-	for i := 0; i < numCores; i++ {
-		go c.burnCPU()
+	go c.control(c.ctx, c.stopCh)
+	for i := 0; i < cores; i++ {
+		go c.burnCPU(c.ctx, c.stopCh)
 	}
 
 	return nil
 }
 
-func (c *CPUBurner) burnCPU() {
+// burnCPU spins for the current duty cycle of every burnPeriod and sleeps
+// for the rest of it.
+func (c *CPUBurner) burnCPU(ctx context.Context, stopCh chan struct{}) {
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-c.stopCh:
+		case <-stopCh:
 			return
 		default:
-			for j := 0; j < 1000000; j++ {
+		}
+
+		c.mu.RLock()
+		busy := time.Duration(c.duty * float64(burnPeriod))
+		c.mu.RUnlock()
+
+		deadline := time.Now().Add(busy)
+		for time.Now().Before(deadline) {
+			for j := 0; j < 1000; j++ {
 				_ = j * j
 			}
-			sleepTime := time.Duration((100-c.targetPercent)*10) * time.Microsecond
-			if sleepTime > 0 {
-				time.Sleep(sleepTime)
-			}
 		}
+		if idle := burnPeriod - busy; idle > 0 {
+			time.Sleep(idle)
+		}
+	}
+}
+
+// control measures the CPU time used by the whole process and corrects the
+// duty cycle so that the burned cores land on the target, whatever the
+// hardware and whatever else the process is doing.
+func (c *CPUBurner) control(ctx context.Context, stopCh chan struct{}) {
+	ticker := time.NewTicker(controlInterval)
+	defer ticker.Stop()
+
+	lastCPU, measurable := processCPUTime()
+	lastWall := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		cpu, _ := processCPUTime()
+
+		c.mu.Lock()
+		target := c.target()
+		if !measurable {
+			c.duty = target / 100
+		} else {
+			c.measured = float64(cpu-lastCPU) / float64(now.Sub(lastWall)) / float64(c.cores) * 100
+			c.duty = math.Min(1, math.Max(0, c.duty+controlGain*(target-c.measured)/100))
+		}
+		c.mu.Unlock()
+
+		lastCPU, lastWall = cpu, now
+	}
+}
+
+// target returns the usage each burned core should reach. A target relative
+// to the CPU limit is spread over the burned cores. Callers must hold c.mu.
+func (c *CPUBurner) target() float64 {
+	if c.ofLimit == nil {
+		return c.targetPercent.At(c.startTime)
 	}
+	perCore := c.ofLimit.At(c.startTime) * c.limits.EffectiveCPUCores / float64(c.cores)
+	return math.Min(perCore, 100)
 }
 
 func (c *CPUBurner) Stop() error {
@@ -113,8 +234,13 @@ func (c *CPUBurner) Status() ScenarioStatus {
 		StartTime: c.startTime,
 		Params:    c.params,
 		Metrics: map[string]float64{
-			"target_percent": float64(c.targetPercent),
-			"num_cores":      float64(runtime.NumCPU()),
+			"target_percent":            c.target(),
+			"measured_percent":          c.measured,
+			"measured_percent_of_limit": c.measured * float64(c.cores) / c.limits.EffectiveCPUCores,
+			"duty_percent":              c.duty * 100,
+			"cores":                     float64(c.cores),
+			"cpu_limit_cores":           c.limits.EffectiveCPUCores,
+			"num_cores":                 float64(runtime.NumCPU()),
 		},
 	}
 }
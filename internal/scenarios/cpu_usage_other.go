@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scenarios
+
+import "time"
+
+// processCPUTime is not available here, so the burner runs open loop.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -2,27 +2,60 @@ package scenarios
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Z3Labs/MockServer/internal/system"
+)
+
+const (
+	CrashExit     = "exit"
+	CrashPanic    = "panic"
+	CrashNilDeref = "nil_deref"
+	CrashSIGKILL  = "sigkill"
+	CrashSIGABRT  = "sigabrt"
+	CrashLogFatal = "log_fatal"
 )
 
+const crashLoopFile = "crash-loop.json"
+
+// CrashLoop is the state of a crash loop, kept on disk so that every restart
+// crashes again until the loop is cleared.
+type CrashLoop struct {
+	Params  map[string]interface{} `json:"params"`
+	Crashes int                    `json:"crashes"`
+}
+
 type CrashSimulator struct {
+	stateDir   string
 	crashDelay int
+	crashType  string
+	exitCode   int
+	loop       bool
+	maxCrashes int
+	crashes    int
 	stopCh     chan struct{}
 	running    atomic.Bool
 	startTime  time.Time
 	params     map[string]interface{}
+	notify     NotifyFunc
 	mu         sync.RWMutex
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
 
-func NewCrashSimulator() *CrashSimulator {
+func NewCrashSimulator(stateDir string) *CrashSimulator {
 	return &CrashSimulator{
-		stopCh: make(chan struct{}),
-		params: make(map[string]interface{}),
+		stateDir: stateDir,
+		stopCh:   make(chan struct{}),
+		params:   make(map[string]interface{}),
 	}
 }
 
@@ -34,6 +67,76 @@ func (c *CrashSimulator) Describe() string {
 	return "Simulates service crash after specified delay"
 }
 
+func (c *CrashSimulator) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "crash_delay", Type: ParamInt, Default: 10, Min: bound(0), Description: "Seconds to wait before the process exits"},
+		{Name: "crash_type", Type: ParamString, Default: CrashExit, Enum: []string{CrashExit, CrashPanic, CrashNilDeref, CrashSIGKILL, CrashSIGABRT, CrashLogFatal}, Description: "How the process dies"},
+		{Name: "exit_code", Type: ParamInt, Default: 1, Min: bound(0), Max: bound(255), Description: "Exit code for crash_type exit"},
+		{Name: "crash_loop", Type: ParamBool, Default: false, Description: "Crash again crash_delay seconds after every restart until the scenario is stopped"},
+		{Name: "max_crashes", Type: ParamInt, Default: 0, Min: bound(0), Description: "crash_loop only: give up after this many crashes, 0 for no limit"},
+	}
+}
+
+func (c *CrashSimulator) Diagnosis(params map[string]interface{}) Diagnosis {
+	delay := paramString(params, "crash_delay")
+	diagnosis := Diagnosis{
+		Category:  "process_crash",
+		RootCause: "process exits unexpectedly",
+		Component: "process",
+	}
+
+	switch params["crash_type"] {
+	case CrashPanic:
+		diagnosis.RootCause = "unrecovered panic in application code"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process exits with code 2 about %s seconds after the fault starts", delay),
+			"\"panic: runtime error: index out of range\" followed by a goroutine stack trace in the logs",
+		}
+	case CrashNilDeref:
+		diagnosis.RootCause = "nil pointer dereference in application code"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process exits with code 2 about %s seconds after the fault starts", delay),
+			"\"invalid memory address or nil pointer dereference [signal SIGSEGV]\" in the logs",
+		}
+	case CrashSIGKILL:
+		diagnosis.RootCause = "process killed with SIGKILL"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process terminated by SIGKILL (exit code 137) about %s seconds after the fault starts", delay),
+			"no shutdown or error logs before the termination",
+		}
+	case CrashSIGABRT:
+		diagnosis.RootCause = "process aborted with SIGABRT"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process terminated by SIGABRT (exit code 134) about %s seconds after the fault starts", delay),
+			"\"SIGABRT: abort\" followed by a dump of all goroutines in the logs",
+		}
+	case CrashLogFatal:
+		diagnosis.RootCause = "fatal error logged by the application"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process exits with code 1 about %s seconds after the fault starts", delay),
+			"a final fatal log line without a stack trace",
+		}
+	default:
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process exits with code %s about %s seconds after the fault starts", paramString(params, "exit_code"), delay),
+		}
+	}
+	diagnosis.Symptoms = append(diagnosis.Symptoms, "container restart and connection errors during the restart")
+
+	if loop, _ := params["crash_loop"].(bool); loop {
+		diagnosis.Category = "crash_loop"
+		diagnosis.Symptoms = append(diagnosis.Symptoms, "restart count keeps growing and the pod ends in CrashLoopBackOff")
+	}
+
+	return diagnosis
+}
+
+func (c *CrashSimulator) SetNotify(notify NotifyFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notify = notify
+}
+
 func (c *CrashSimulator) Start(ctx context.Context, params map[string]interface{}) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -47,31 +150,202 @@ func (c *CrashSimulator) Start(ctx context.Context, params map[string]interface{
 	c.params = params
 
 	crashDelay := 10
-	if cd, ok := params["crash_delay"].(float64); ok {
+	if cd, ok := toFloat(params["crash_delay"]); ok {
 		crashDelay = int(cd)
-	} else if cd, ok := params["crash_delay"].(int); ok {
-		crashDelay = cd
 	}
 	c.crashDelay = crashDelay
 
+	c.crashType = CrashExit
+	if t, ok := params["crash_type"].(string); ok {
+		c.crashType = t
+	}
+	c.exitCode = 1
+	if code, ok := toFloat(params["exit_code"]); ok {
+		c.exitCode = int(code)
+	}
+	c.maxCrashes = 0
+	if n, ok := toFloat(params["max_crashes"]); ok {
+		c.maxCrashes = int(n)
+	}
+	c.loop, _ = params["crash_loop"].(bool)
+
+	// A restarted loop keeps counting, a new loop starts from zero.
+	c.crashes = 0
+	if c.loop {
+		if state, ok := LoadCrashLoop(c.stateDir); ok {
+			c.crashes = state.Crashes
+		}
+		if err := c.saveLoop(c.crashes); err != nil {
+			return fmt.Errorf("save crash loop: %w", err)
+		}
+	} else {
+		ClearCrashLoop(c.stateDir)
+	}
+
 	c.running.Store(true)
-	go c.scheduleCrash()
+	go c.scheduleCrash(c.ctx, c.stopCh)
 
 	return nil
 }
 
-func (c *CrashSimulator) scheduleCrash() {
+func (c *CrashSimulator) scheduleCrash(ctx context.Context, stopCh chan struct{}) {
 	timer := time.NewTimer(time.Duration(c.crashDelay) * time.Second)
 	defer timer.Stop()
 
 	select {
-	case <-c.ctx.Done():
+	case <-ctx.Done():
 		return
-	case <-c.stopCh:
+	case <-stopCh:
 		return
 	case <-timer.C:
-		os.Exit(1)
 	}
+
+	c.mu.RLock()
+	notify := c.notify
+	crashType, exitCode := c.crashType, c.exitCode
+	crashes := c.crashes + 1
+	loop := c.loop
+	c.mu.RUnlock()
+
+	if loop {
+		c.saveLoop(crashes)
+	}
+	system.WriteExitMarker(c.stateDir, system.ExitMarker{
+		Reason:           "crash_" + crashType,
+		Scenario:         c.Name(),
+		ExpectedExitCode: expectedExitCode(crashType, exitCode),
+		Signal:           crashSignal(crashType),
+		Details: map[string]interface{}{
+			"crash_loop": loop,
+			"crashes":    crashes,
+		},
+	})
+	if notify != nil {
+		notify(EventCrashImminent, map[string]float64{
+			"crash_delay": float64(c.crashDelay),
+			"crashes":     float64(crashes),
+		})
+	}
+
+	crash(crashType, exitCode)
+}
+
+// crash takes the process down the way crashType describes. Every path ends
+// in os.Exit in case the platform does not support the signal.
+func crash(crashType string, exitCode int) {
+	switch crashType {
+	case CrashPanic:
+		processOrderBatch([]orderItem{{sku: "A-1", quantity: 1}})
+	case CrashNilDeref:
+		var record *orderRecord
+		fmt.Println(record.customer.name)
+	case CrashSIGKILL:
+		raiseSignal(CrashSIGKILL)
+	case CrashSIGABRT:
+		// "crash" makes the runtime dump every goroutine and then die by the
+		// signal instead of exiting with code 2.
+		debug.SetTraceback("crash")
+		raiseSignal(CrashSIGABRT)
+	case CrashLogFatal:
+		log.Fatalf("fatal: failed to reload configuration: open %s: no such file or directory",
+			filepath.Join(os.TempDir(), "mockserver", "runtime.yaml"))
+	}
+
+	time.Sleep(time.Second)
+	os.Exit(exitCode)
+}
+
+type orderItem struct {
+	sku      string
+	quantity int
+}
+
+type orderRecord struct {
+	customer *struct{ name string }
+}
+
+// processOrderBatch and applyDiscounts give the panic a stack trace that
+// looks like application code rather than a deliberate panic call.
+func processOrderBatch(items []orderItem) int {
+	total := 0
+	for i := range items {
+		total += applyDiscounts(items, i)
+	}
+	return total
+}
+
+func applyDiscounts(items []orderItem, i int) int {
+	return items[i].quantity * items[i+len(items)].quantity
+}
+
+func expectedExitCode(crashType string, exitCode int) int {
+	switch crashType {
+	case CrashPanic, CrashNilDeref:
+		return 2
+	case CrashSIGKILL:
+		return 137
+	case CrashSIGABRT:
+		return 134
+	case CrashLogFatal:
+		return 1
+	default:
+		return exitCode
+	}
+}
+
+func crashSignal(crashType string) string {
+	switch crashType {
+	case CrashNilDeref:
+		return "SIGSEGV"
+	case CrashSIGKILL:
+		return "SIGKILL"
+	case CrashSIGABRT:
+		return "SIGABRT"
+	default:
+		return ""
+	}
+}
+
+func (c *CrashSimulator) saveLoop(crashes int) error {
+	if c.maxCrashes > 0 && crashes >= c.maxCrashes {
+		// The crash about to happen is the last one, nothing to resume.
+		ClearCrashLoop(c.stateDir)
+		return nil
+	}
+
+	data, err := json.Marshal(CrashLoop{Params: c.params, Crashes: crashes})
+	if err != nil {
+		return err
+	}
+	dir := c.stateDir
+	if dir == "" {
+		dir = system.DefaultStateDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, crashLoopFile), data, 0o644)
+}
+
+// LoadCrashLoop returns the crash loop left by a previous run, if any.
+func LoadCrashLoop(stateDir string) (CrashLoop, bool) {
+	if stateDir == "" {
+		stateDir = system.DefaultStateDir
+	}
+
+	var state CrashLoop
+	data, err := os.ReadFile(filepath.Join(stateDir, crashLoopFile))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return CrashLoop{}, false
+	}
+	return state, true
+}
+
+func ClearCrashLoop(stateDir string) {
+	if stateDir == "" {
+		stateDir = system.DefaultStateDir
+	}
+	os.Remove(filepath.Join(stateDir, crashLoopFile))
 }
 
 func (c *CrashSimulator) Stop() error {
@@ -91,6 +365,9 @@ func (c *CrashSimulator) stop() error {
 	}
 	close(c.stopCh)
 	c.stopCh = make(chan struct{})
+	if c.loop {
+		ClearCrashLoop(c.stateDir)
+	}
 
 	return nil
 }
@@ -105,6 +382,8 @@ func (c *CrashSimulator) Status() ScenarioStatus {
 		Params:    c.params,
 		Metrics: map[string]float64{
 			"crash_delay": float64(c.crashDelay),
+			"exit_code":   float64(expectedExitCode(c.crashType, c.exitCode)),
+			"crashes":     float64(c.crashes),
 		},
 	}
 }
@@ -2,6 +2,7 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,6 +10,8 @@ import (
 
 type DependencyFailure struct {
 	failureType string
+	timeout     time.Duration
+	slow        time.Duration
 	stopCh      chan struct{}
 	running     atomic.Bool
 	startTime   time.Time
@@ -33,6 +36,35 @@ func (d *DependencyFailure) Describe() string {
 	return "Simulates dependency service failures (timeout, error, slow response)"
 }
 
+func (d *DependencyFailure) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "failure_type", Type: ParamString, Default: "timeout", Enum: []string{"timeout", "error", "slow"}, Description: "How the mock dependency endpoint misbehaves"},
+		{Name: "timeout_ms", Type: ParamInt, Default: 30000, Min: bound(0), Description: "timeout only: how long the dependency hangs before answering"},
+		{Name: "slow_ms", Type: ParamInt, Default: 3000, Min: bound(0), Description: "slow only: response time of the dependency"},
+	}
+}
+
+func (d *DependencyFailure) Diagnosis(params map[string]interface{}) Diagnosis {
+	failureType, _ := params["failure_type"].(string)
+
+	var symptoms []string
+	switch failureType {
+	case "error":
+		symptoms = []string{"dependency calls return errors", "increased error rate on dependent endpoints"}
+	case "slow":
+		symptoms = []string{fmt.Sprintf("dependency calls take about %s ms", paramString(params, "slow_ms")), "increased latency on dependent endpoints"}
+	default:
+		symptoms = []string{fmt.Sprintf("dependency calls hang for %s ms", paramString(params, "timeout_ms")), "timeouts on dependent endpoints"}
+	}
+
+	return Diagnosis{
+		Category:  "dependency_failure",
+		RootCause: fmt.Sprintf("downstream dependency failure (%s)", failureType),
+		Component: "dependency",
+		Symptoms:  symptoms,
+	}
+}
+
 func (d *DependencyFailure) Start(ctx context.Context, params map[string]interface{}) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -50,6 +82,14 @@ func (d *DependencyFailure) Start(ctx context.Context, params map[string]interfa
 		d.failureType = ft
 	}
 
+	d.timeout, d.slow = 30*time.Second, 3*time.Second
+	if ms, ok := toFloat(params["timeout_ms"]); ok {
+		d.timeout = time.Duration(ms) * time.Millisecond
+	}
+	if ms, ok := toFloat(params["slow_ms"]); ok {
+		d.slow = time.Duration(ms) * time.Millisecond
+	}
+
 	d.running.Store(true)
 
 	return nil
@@ -84,16 +124,29 @@ func (d *DependencyFailure) Status() ScenarioStatus {
 		Running:   d.running.Load(),
 		StartTime: d.startTime,
 		Params:    d.params,
-		Metrics:   map[string]float64{},
+		Metrics: map[string]float64{
+			"timeout_ms": float64(d.timeout.Milliseconds()),
+			"slow_ms":    float64(d.slow.Milliseconds()),
+		},
 	}
 }
 
-func (d *DependencyFailure) GetFailureType() (string, bool) {
+// GetFailureType returns the active failure and, for timeout and slow, how
+// long the dependency takes to answer.
+func (d *DependencyFailure) GetFailureType() (string, time.Duration, bool) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
 	if !d.running.Load() {
-		return "", false
+		return "", 0, false
+	}
+
+	switch d.failureType {
+	case "timeout":
+		return d.failureType, d.timeout, true
+	case "slow":
+		return d.failureType, d.slow, true
+	default:
+		return d.failureType, 0, true
 	}
-	return d.failureType, true
 }
@@ -37,6 +37,24 @@ func (d *DiskIO) Describe() string {
 	return "Generates high disk IO by writing data at specified rate"
 }
 
+func (d *DiskIO) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "write_rate_mb", Type: ParamInt, Default: 50, Min: bound(1), Description: "Data written and synced per second, in MB"},
+	}
+}
+
+func (d *DiskIO) Diagnosis(params map[string]interface{}) Diagnosis {
+	return Diagnosis{
+		Category:  "disk_io_saturation",
+		RootCause: "heavy synchronous disk writes",
+		Component: "disk",
+		Symptoms: []string{
+			fmt.Sprintf("disk write throughput around %s MB/s", paramString(params, "write_rate_mb")),
+			"high iowait and slower disk operations",
+		},
+	}
+}
+
 func (d *DiskIO) Start(ctx context.Context, params map[string]interface{}) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -2,6 +2,7 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -34,6 +35,24 @@ func (g *GoroutineLeak) Describe() string {
 	return "Creates goroutines that never exit, causing goroutine leak"
 }
 
+func (g *GoroutineLeak) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "goroutines_per_second", Type: ParamInt, Default: 100, Min: bound(0), Description: "Goroutines leaked per second"},
+	}
+}
+
+func (g *GoroutineLeak) Diagnosis(params map[string]interface{}) Diagnosis {
+	return Diagnosis{
+		Category:  "goroutine_leak",
+		RootCause: "goroutine leak: goroutines are started and never exit",
+		Component: "runtime",
+		Symptoms: []string{
+			fmt.Sprintf("goroutine count rising by about %s per second", paramString(params, "goroutines_per_second")),
+			"slowly growing memory usage",
+		},
+	}
+}
+
 func (g *GoroutineLeak) Start(ctx context.Context, params map[string]interface{}) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
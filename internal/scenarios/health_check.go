@@ -2,16 +2,38 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Z3Labs/MockServer/internal/health"
+)
+
+const (
+	ProbeLiveness  = "liveness"
+	ProbeReadiness = "readiness"
+	ProbeStartup   = "startup"
 )
 
+// HealthCheckFailure fails one or more health probes. The health_check
+// scenario fails liveness and readiness together, the <probe>_probe
+// scenarios fail a single probe.
 type HealthCheckFailure struct {
+	name        string
+	probes      []string
 	failureMode string
 	statusCode  int
 	failRate    float64
+	delay       time.Duration
+	upPeriod    time.Duration
+	downPeriod  time.Duration
+	pattern     string
+	positions   map[string]int
+	components  map[string]health.Check
+	rng         *rand.Rand
 	stopCh      chan struct{}
 	running     atomic.Bool
 	startTime   time.Time
@@ -22,7 +44,18 @@ type HealthCheckFailure struct {
 }
 
 func NewHealthCheckFailure() *HealthCheckFailure {
+	return newHealthCheckFailure("health_check", ProbeLiveness, ProbeReadiness)
+}
+
+// NewProbeFailure returns the scenario that fails only the given probe.
+func NewProbeFailure(probe string) *HealthCheckFailure {
+	return newHealthCheckFailure(probe+"_probe", probe)
+}
+
+func newHealthCheckFailure(name string, probes ...string) *HealthCheckFailure {
 	return &HealthCheckFailure{
+		name:       name,
+		probes:     probes,
 		stopCh:     make(chan struct{}),
 		params:     make(map[string]interface{}),
 		statusCode: 503,
@@ -30,13 +63,96 @@ func NewHealthCheckFailure() *HealthCheckFailure {
 }
 
 func (h *HealthCheckFailure) Name() string {
-	return "health_check"
+	return h.name
 }
 
 func (h *HealthCheckFailure) Describe() string {
+	if len(h.probes) == 1 {
+		return fmt.Sprintf("Controls the %s probe endpoint to return failures", h.probes[0])
+	}
 	return "Controls health check endpoint to return failures"
 }
 
+func (h *HealthCheckFailure) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "failure_mode", Type: ParamString, Default: "always", Enum: []string{"always", "intermittent", "delayed", "flapping", "pattern"}, Description: "How the health check fails"},
+		{Name: "status_code", Type: ParamInt, Default: 503, Min: bound(100), Max: bound(599), Description: "Status code of failing probes"},
+		{Name: "fail_rate", Type: ParamFloat, Default: 0.5, Min: bound(0), Max: bound(1), Description: "Probability of failure in intermittent mode"},
+		{Name: "seed", Type: ParamInt, Default: 0, Description: "intermittent only: seed for a reproducible failure sequence, 0 for a random one"},
+		{Name: "delay_ms", Type: ParamInt, Default: 10000, Min: bound(0), Description: "Response delay in delayed mode"},
+		{Name: "up_seconds", Type: ParamInt, Default: 30, Min: bound(1), Description: "flapping only: length of each healthy period"},
+		{Name: "down_seconds", Type: ParamInt, Default: 30, Min: bound(1), Description: "flapping only: length of each failing period, the first period is a failing one"},
+		{Name: "pattern", Type: ParamString, Default: "UUUD", Pattern: "^[UD]+$", Description: "pattern only: one U (up) or D (down) per probe request, repeated"},
+		{Name: "components", Type: ParamString, Default: "", Pattern: "^((db|cache|downstream|disk)(,(db|cache|downstream|disk))*)?$", Description: "Comma separated components reported as failed by failing probes, empty to fail the service as a whole"},
+		{Name: "component_status", Type: ParamString, Default: health.StatusDown, Enum: []string{health.StatusDown, health.StatusDegraded}, Description: "Status reported for the failed components"},
+		{Name: "component_latency_ms", Type: ParamInt, Default: 3000, Min: bound(0), Description: "Check latency reported for the failed components"},
+		{Name: "component_message", Type: ParamString, Default: "", Description: "Message reported for the failed components, a typical error when empty"},
+	}
+}
+
+func (h *HealthCheckFailure) Diagnosis(params map[string]interface{}) Diagnosis {
+	mode, _ := params["failure_mode"].(string)
+	failRate, _ := params["fail_rate"].(float64)
+
+	var symptoms []string
+	switch mode {
+	case "intermittent":
+		symptoms = []string{
+			fmt.Sprintf("health check fails in about %.0f%% of probes", failRate*100),
+			"instance flapping between ready and not ready",
+		}
+	case "delayed":
+		symptoms = []string{
+			fmt.Sprintf("health check responses take %s ms and time out", paramString(params, "delay_ms")),
+			"probe failures without error status codes",
+		}
+	case "flapping":
+		symptoms = []string{
+			fmt.Sprintf("health check alternates between HTTP %s for %s seconds and healthy for %s seconds",
+				paramString(params, "status_code"), paramString(params, "down_seconds"), paramString(params, "up_seconds")),
+			"instance flapping between ready and not ready on a fixed period",
+		}
+	case "pattern":
+		symptoms = []string{
+			fmt.Sprintf("health check results follow the repeating sequence %s (D returns HTTP %s)",
+				paramString(params, "pattern"), paramString(params, "status_code")),
+			"instance flapping between ready and not ready",
+		}
+	default:
+		symptoms = []string{
+			fmt.Sprintf("health check returns HTTP %s", paramString(params, "status_code")),
+		}
+	}
+
+	rootCause := "service health check is failing"
+	if len(h.probes) == 1 {
+		rootCause = fmt.Sprintf("%s probe is failing", h.probes[0])
+	}
+	component := "health_check"
+	if names, _ := params["components"].(string); names != "" {
+		component = names
+		rootCause = fmt.Sprintf("dependency %s reported %s by the health check", names, paramString(params, "component_status"))
+		symptoms = append(symptoms, fmt.Sprintf("health check body lists %s as %s", names, paramString(params, "component_status")))
+	}
+	for _, probe := range h.probes {
+		switch probe {
+		case ProbeLiveness:
+			symptoms = append(symptoms, "container restarted by the kubelet after liveness probe failures")
+		case ProbeReadiness:
+			symptoms = append(symptoms, "instance removed from service endpoints while the container keeps running")
+		case ProbeStartup:
+			symptoms = append(symptoms, "container restarted before it ever becomes ready, startup probe failures in events")
+		}
+	}
+
+	return Diagnosis{
+		Category:  "health_check_failure",
+		RootCause: rootCause,
+		Component: component,
+		Symptoms:  symptoms,
+	}
+}
+
 func (h *HealthCheckFailure) Start(ctx context.Context, params map[string]interface{}) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -66,6 +182,32 @@ func (h *HealthCheckFailure) Start(ctx context.Context, params map[string]interf
 		h.failRate = fr
 	}
 
+	seed := time.Now().UnixNano()
+	if n, ok := toFloat(params["seed"]); ok && n != 0 {
+		seed = int64(n)
+	}
+	h.rng = rand.New(rand.NewSource(seed))
+
+	h.delay = 10 * time.Second
+	if n, ok := toFloat(params["delay_ms"]); ok {
+		h.delay = time.Duration(n) * time.Millisecond
+	}
+
+	h.upPeriod, h.downPeriod = 30*time.Second, 30*time.Second
+	if n, ok := toFloat(params["up_seconds"]); ok {
+		h.upPeriod = time.Duration(n) * time.Second
+	}
+	if n, ok := toFloat(params["down_seconds"]); ok {
+		h.downPeriod = time.Duration(n) * time.Second
+	}
+
+	h.pattern = "UUUD"
+	if p, ok := params["pattern"].(string); ok && p != "" {
+		h.pattern = p
+	}
+	h.positions = make(map[string]int)
+	h.components = componentFaults(params)
+
 	h.running.Store(true)
 
 	return nil
@@ -103,13 +245,67 @@ func (h *HealthCheckFailure) Status() ScenarioStatus {
 		Metrics: map[string]float64{
 			"status_code": float64(h.statusCode),
 			"fail_rate":   h.failRate,
+			"delay_ms":    float64(h.delay.Milliseconds()),
 		},
 	}
 }
 
-func (h *HealthCheckFailure) ShouldFail() (bool, int, time.Duration) {
+// componentMessages are the errors reported by default for failed components.
+var componentMessages = map[string]map[string]string{
+	health.StatusDown: {
+		"db":         "dial tcp 10.0.3.12:3306: connect: connection refused",
+		"cache":      "dial tcp 10.0.3.20:6379: i/o timeout",
+		"downstream": "GET http://inventory/api/v1/ping: 503 Service Unavailable",
+		"disk":       "write /data/healthcheck: no space left on device",
+	},
+	health.StatusDegraded: {
+		"db":         "slow queries: p99 above 1s",
+		"cache":      "high miss rate: 62%",
+		"downstream": "elevated error rate: 8% of requests failing",
+		"disk":       "disk usage above 90%",
+	},
+}
+
+func componentFaults(params map[string]interface{}) map[string]health.Check {
+	names, _ := params["components"].(string)
+	if names == "" {
+		return nil
+	}
+
+	status := health.StatusDown
+	if s, ok := params["component_status"].(string); ok {
+		status = s
+	}
+	latency := 3000.0
+	if n, ok := toFloat(params["component_latency_ms"]); ok {
+		latency = n
+	}
+	message, _ := params["component_message"].(string)
+
+	faults := make(map[string]health.Check)
+	for _, name := range strings.Split(names, ",") {
+		check := health.Check{Status: status, LatencyMs: latency, Message: message}
+		if check.Message == "" {
+			check.Message = componentMessages[status][name]
+		}
+		faults[name] = check
+	}
+	return faults
+}
+
+// ComponentFaults returns the checks of the components failed by the
+// scenario, nil when it fails the service as a whole.
+func (h *HealthCheckFailure) ComponentFaults() map[string]health.Check {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	return h.components
+}
+
+// ShouldFail decides the outcome of one request to the given probe. Pattern
+// positions are kept per probe so that each endpoint follows the sequence.
+func (h *HealthCheckFailure) ShouldFail(probe string) (bool, int, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	if !h.running.Load() {
 		return false, 200, 0
@@ -119,12 +315,25 @@ func (h *HealthCheckFailure) ShouldFail() (bool, int, time.Duration) {
 	case "always":
 		return true, h.statusCode, 0
 	case "intermittent":
-		if rand.Float64() < h.failRate {
-			return true, 503, 0
+		if h.rng.Float64() < h.failRate {
+			return true, h.statusCode, 0
 		}
 		return false, 200, 0
 	case "delayed":
-		return false, 200, 10 * time.Second
+		return false, 200, h.delay
+	case "flapping":
+		cycle := h.downPeriod + h.upPeriod
+		if time.Since(h.startTime)%cycle < h.downPeriod {
+			return true, h.statusCode, 0
+		}
+		return false, 200, 0
+	case "pattern":
+		pos := h.positions[probe]
+		h.positions[probe] = (pos + 1) % len(h.pattern)
+		if h.pattern[pos] == 'D' {
+			return true, h.statusCode, 0
+		}
+		return false, 200, 0
 	default:
 		return false, 200, 0
 	}
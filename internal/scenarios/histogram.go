@@ -0,0 +1,96 @@
+package scenarios
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// quantileWindow is how many recent observations quantiles are computed over.
+const quantileWindow = 10000
+
+// latencyBuckets are the upper bounds, in milliseconds, of the injected
+// latency histogram.
+var latencyBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+type HistogramBucket struct {
+	// Le is the inclusive upper bound in milliseconds, "+Inf" for the last
+	// bucket, which JSON cannot encode as a number.
+	Le   string  `json:"le"`
+	LeMs float64 `json:"-"`
+	// Count is cumulative, as in a Prometheus histogram.
+	Count int64 `json:"count"`
+}
+
+// Histogram counts observed durations in fixed buckets.
+type Histogram struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Count   int64             `json:"count"`
+	SumMs   float64           `json:"sum_ms"`
+	MaxMs   float64           `json:"max_ms"`
+
+	// recent holds the window in arrival order and sorted the same values in
+	// order, so that quantiles need no sort.
+	recent []float64
+	sorted []float64
+	next   int
+}
+
+func newLatencyHistogram() *Histogram {
+	h := &Histogram{Buckets: make([]HistogramBucket, len(latencyBuckets)+1)}
+	for i, le := range latencyBuckets {
+		h.Buckets[i].LeMs = le
+		h.Buckets[i].Le = strconv.FormatFloat(le, 'f', -1, 64)
+	}
+	h.Buckets[len(latencyBuckets)].LeMs = math.Inf(1)
+	h.Buckets[len(latencyBuckets)].Le = "+Inf"
+	return h
+}
+
+func (h *Histogram) Observe(ms float64) {
+	for i := range h.Buckets {
+		if ms <= h.Buckets[i].LeMs {
+			h.Buckets[i].Count++
+		}
+	}
+	h.Count++
+	h.SumMs += ms
+	h.MaxMs = math.Max(h.MaxMs, ms)
+
+	if len(h.recent) < quantileWindow {
+		h.recent = append(h.recent, ms)
+	} else {
+		h.remove(h.recent[h.next])
+		h.recent[h.next] = ms
+		h.next = (h.next + 1) % quantileWindow
+	}
+	h.insert(ms)
+}
+
+func (h *Histogram) insert(ms float64) {
+	i := sort.SearchFloat64s(h.sorted, ms)
+	h.sorted = append(h.sorted, 0)
+	copy(h.sorted[i+1:], h.sorted[i:])
+	h.sorted[i] = ms
+}
+
+func (h *Histogram) remove(ms float64) {
+	i := sort.SearchFloat64s(h.sorted, ms)
+	h.sorted = append(h.sorted[:i], h.sorted[i+1:]...)
+}
+
+// Quantile returns the q-quantile of the most recent observations.
+func (h *Histogram) Quantile(q float64) float64 {
+	if len(h.sorted) == 0 {
+		return 0
+	}
+	return h.sorted[int(q*float64(len(h.sorted)-1))]
+}
+
+func (h *Histogram) copy() *Histogram {
+	c := *h
+	c.Buckets = append([]HistogramBucket(nil), h.Buckets...)
+	c.recent = nil
+	c.sorted = nil
+	return &c
+}
@@ -0,0 +1,33 @@
+package scenarios
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHistogramQuantile(t *testing.T) {
+	h := newLatencyHistogram()
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("empty Quantile(0.5) = %v, want 0", got)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var observed []float64
+	for i := 0; i < 2*quantileWindow+500; i++ {
+		ms := float64(rng.Intn(1000))
+		h.Observe(ms)
+		observed = append(observed, ms)
+	}
+
+	window := append([]float64(nil), observed[len(observed)-quantileWindow:]...)
+	sort.Float64s(window)
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		if got, want := h.Quantile(q), window[int(q*float64(len(window)-1))]; got != want {
+			t.Errorf("Quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+	if h.Count != int64(len(observed)) {
+		t.Errorf("Count = %d, want %d", h.Count, len(observed))
+	}
+}
@@ -0,0 +1,264 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// InjectedError is the response served in place of a matching request.
+type InjectedError struct {
+	Code       int
+	RetryAfter int
+	Body       string
+}
+
+type weightedCode struct {
+	code   int
+	weight float64
+}
+
+type HTTPErrors struct {
+	errorRate  NumericParam
+	codes      []weightedCode
+	retryAfter int
+	body       string
+	rng        *rand.Rand
+	matcher    *RequestMatcher
+	matched    int64
+	served     map[int]int64
+	stopCh     chan struct{}
+	running    atomic.Bool
+	startTime  time.Time
+	params     map[string]interface{}
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+func NewHTTPErrors() *HTTPErrors {
+	return &HTTPErrors{
+		stopCh: make(chan struct{}),
+		params: make(map[string]interface{}),
+	}
+}
+
+func (h *HTTPErrors) Name() string {
+	return "http_errors"
+}
+
+func (h *HTTPErrors) Describe() string {
+	return "Makes a fraction of HTTP requests fail with chosen status codes"
+}
+
+func (h *HTTPErrors) Schema() ParamSchema {
+	schema := ParamSchema{
+		{Name: "error_rate", Type: ParamFloat, Default: 10, Min: bound(0), Max: bound(100), Rampable: true, Description: "Percentage of matching requests that fail"},
+		{Name: "status_codes", Type: ParamString, Default: "500", Pattern: `^[45]\d\d(:\d+)?(,[45]\d\d(:\d+)?)*$`, Description: "Comma separated status codes of the failing requests, each with an optional :weight (default 1), e.g. 500:5,503:3,429:2"},
+		{Name: "retry_after", Type: ParamInt, Default: 0, Min: bound(0), Description: "Retry-After header in seconds on 429 and 503 responses, 0 for none"},
+		{Name: "body", Type: ParamString, Default: "", Description: "Response body of the failing requests, a JSON error when empty"},
+		{Name: "seed", Type: ParamInt, Default: 0, Description: "Seed for a reproducible error sequence, 0 for a random one"},
+	}
+	// error_rate already samples the matching requests.
+	for _, spec := range matchParams() {
+		if spec.Name != "percentage" {
+			schema = append(schema, spec)
+		}
+	}
+	return schema
+}
+
+func (h *HTTPErrors) Validate(params map[string]interface{}) []FieldError {
+	errs := validateMatch(params)
+	if _, err := parseWeightedCodes(params["status_codes"]); err != nil {
+		errs = append(errs, FieldError{Field: "status_codes", Message: err.Error()})
+	}
+	return errs
+}
+
+func (h *HTTPErrors) Diagnosis(params map[string]interface{}) Diagnosis {
+	codes, _ := params["status_codes"].(string)
+
+	symptoms := []string{
+		fmt.Sprintf("%s%% of requests fail with HTTP %s", paramString(params, "error_rate"), strings.ReplaceAll(codes, ",", ", ")),
+		"error rate SLO burn while latency and resource usage stay normal",
+	}
+	if retryAfter, _ := toFloat(params["retry_after"]); retryAfter > 0 {
+		symptoms = append(symptoms, fmt.Sprintf("429 and 503 responses ask clients to retry after %v seconds", retryAfter))
+	}
+	if scope := matchScope(params); scope != "" {
+		symptoms = append(symptoms, "only affects "+scope+", other requests succeed")
+	}
+
+	return Diagnosis{
+		Category:  "http_errors",
+		RootCause: "service returning HTTP error responses",
+		Component: "http",
+		Symptoms:  symptoms,
+	}
+}
+
+func (h *HTTPErrors) Start(ctx context.Context, params map[string]interface{}) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	errorRate, err := parseNumericParam(params, "error_rate", 10)
+	if err != nil {
+		return err
+	}
+	codes, err := parseWeightedCodes(params["status_codes"])
+	if err != nil {
+		return err
+	}
+	matcher, err := parseRequestMatcher(params)
+	if err != nil {
+		return err
+	}
+
+	if h.running.Load() {
+		h.stop()
+	}
+
+	h.ctx, h.cancel = context.WithCancel(ctx)
+	h.startTime = time.Now()
+	h.params = params
+	h.errorRate = errorRate
+	h.codes = codes
+
+	h.retryAfter = 0
+	if v, ok := toFloat(params["retry_after"]); ok {
+		h.retryAfter = int(v)
+	}
+	h.body, _ = params["body"].(string)
+
+	seed := time.Now().UnixNano()
+	if v, ok := toFloat(params["seed"]); ok && v != 0 {
+		seed = int64(v)
+	}
+	h.rng = rand.New(rand.NewSource(seed))
+	h.matcher = matcher
+	h.matched = 0
+	h.served = make(map[int]int64)
+
+	h.running.Store(true)
+
+	return nil
+}
+
+func (h *HTTPErrors) Stop() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stop()
+}
+
+func (h *HTTPErrors) stop() error {
+	if !h.running.Load() {
+		return nil
+	}
+
+	h.running.Store(false)
+	if h.cancel != nil {
+		h.cancel()
+	}
+	close(h.stopCh)
+	h.stopCh = make(chan struct{})
+
+	return nil
+}
+
+func (h *HTTPErrors) Status() ScenarioStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status := ScenarioStatus{
+		Running:   h.running.Load(),
+		StartTime: h.startTime,
+		Params:    h.params,
+		Metrics: map[string]float64{
+			"error_rate":       h.errorRate.At(h.startTime),
+			"matched_requests": float64(h.matched),
+		},
+	}
+	var total int64
+	for code, n := range h.served {
+		status.Metrics[fmt.Sprintf("errors_%d", code)] = float64(n)
+		total += n
+	}
+	status.Metrics["errors_served"] = float64(total)
+	return status
+}
+
+// GetError decides whether r fails and with which response. It returns nil
+// when r does not match or was spared by the error rate.
+func (h *HTTPErrors) GetError(r *http.Request) *InjectedError {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.running.Load() || !h.matcher.Match(r, h.rng) {
+		return nil
+	}
+	h.matched++
+	if h.rng.Float64()*100 >= h.errorRate.At(h.startTime) {
+		return nil
+	}
+
+	code := h.pickCode()
+	h.served[code]++
+
+	injected := &InjectedError{Code: code, Body: h.body}
+	if code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable {
+		injected.RetryAfter = h.retryAfter
+	}
+	return injected
+}
+
+func (h *HTTPErrors) pickCode() int {
+	var total float64
+	for _, c := range h.codes {
+		total += c.weight
+	}
+
+	n := h.rng.Float64() * total
+	for _, c := range h.codes {
+		if n < c.weight {
+			return c.code
+		}
+		n -= c.weight
+	}
+	return h.codes[len(h.codes)-1].code
+}
+
+func parseWeightedCodes(value interface{}) ([]weightedCode, error) {
+	str, _ := value.(string)
+	if str == "" {
+		return []weightedCode{{code: http.StatusInternalServerError, weight: 1}}, nil
+	}
+
+	var codes []weightedCode
+	var total float64
+	for _, item := range strings.Split(str, ",") {
+		codeStr, weightStr, hasWeight := strings.Cut(strings.TrimSpace(item), ":")
+		code, err := strconv.Atoi(codeStr)
+		if err != nil || code < 400 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q in status_codes", codeStr)
+		}
+		weight := 1.0
+		if hasWeight {
+			if weight, err = strconv.ParseFloat(weightStr, 64); err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight %q in status_codes", weightStr)
+			}
+		}
+		codes = append(codes, weightedCode{code: code, weight: weight})
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("status_codes weights must not all be 0")
+	}
+	return codes, nil
+}
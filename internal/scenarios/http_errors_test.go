@@ -0,0 +1,85 @@
+package scenarios
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseWeightedCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    []weightedCode
+		wantErr bool
+	}{
+		{name: "default", value: nil, want: []weightedCode{{code: 500, weight: 1}}},
+		{name: "empty", value: "", want: []weightedCode{{code: 500, weight: 1}}},
+		{name: "single", value: "503", want: []weightedCode{{code: 503, weight: 1}}},
+		{name: "weighted", value: "500:3, 503:1,429:0.5", want: []weightedCode{{code: 500, weight: 3}, {code: 503, weight: 1}, {code: 429, weight: 0.5}}},
+		{name: "some zero weights", value: "500:0,503:2", want: []weightedCode{{code: 500, weight: 0}, {code: 503, weight: 2}}},
+		{name: "success code", value: "200", wantErr: true},
+		{name: "out of range", value: "600", wantErr: true},
+		{name: "not a number", value: "5xx", wantErr: true},
+		{name: "negative weight", value: "500:-1", wantErr: true},
+		{name: "invalid weight", value: "500:x", wantErr: true},
+		{name: "all zero weights", value: "500:0,503:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes, err := parseWeightedCodes(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseWeightedCodes(%v) = %v, want an error", tt.value, codes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseWeightedCodes(%v) error: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("parseWeightedCodes(%v) = %v, want %v", tt.value, codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes string
+		want  map[int]float64
+	}{
+		{name: "single", codes: "503", want: map[int]float64{503: 1}},
+		{name: "weighted", codes: "500:3,503:1", want: map[int]float64{500: 0.75, 503: 0.25}},
+		{name: "zero weight never picked", codes: "500:0,429:1,503:1", want: map[int]float64{429: 0.5, 503: 0.5}},
+	}
+
+	const draws = 20000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes, err := parseWeightedCodes(tt.codes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := &HTTPErrors{codes: codes, rng: rand.New(rand.NewSource(1))}
+
+			counts := make(map[int]int)
+			for i := 0; i < draws; i++ {
+				counts[h.pickCode()]++
+			}
+			for code := range counts {
+				if _, ok := tt.want[code]; !ok {
+					t.Errorf("picked unexpected code %d", code)
+				}
+			}
+			for code, share := range tt.want {
+				if got := float64(counts[code]) / draws; math.Abs(got-share) > 0.02 {
+					t.Errorf("code %d picked %.3f of the time, want %.3f", code, got, share)
+				}
+			}
+		})
+	}
+}
@@ -11,6 +11,38 @@ type Scenario interface {
 	Stop() error
 	Status() ScenarioStatus
 	Describe() string
+	Schema() ParamSchema
+	Diagnosis(params map[string]interface{}) Diagnosis
+}
+
+const (
+	EventTargetReached = "target_reached"
+	EventCrashImminent = "crash_imminent"
+)
+
+// NotifyFunc reports a milestone of a running scenario, such as reaching its
+// target, together with its metrics at that moment.
+type NotifyFunc func(event string, metrics map[string]float64)
+
+// Notifier is implemented by scenarios that report milestones while running.
+type Notifier interface {
+	SetNotify(notify NotifyFunc)
+}
+
+// Validator is implemented by scenarios with params that are only valid
+// together or on some hosts. Validate gets the normalized params, and the
+// fields it reports reject the request before anything starts.
+type Validator interface {
+	Validate(params map[string]interface{}) []FieldError
+}
+
+// Diagnosis is the ground truth a correct analysis of the scenario should
+// reach: what went wrong, where, and what it looks like from the outside.
+type Diagnosis struct {
+	Category  string   `json:"category"`
+	RootCause string   `json:"root_cause"`
+	Component string   `json:"component"`
+	Symptoms  []string `json:"symptoms"`
 }
 
 type ScenarioStatus struct {
@@ -18,4 +50,5 @@ type ScenarioStatus struct {
 	StartTime time.Time              `json:"start_time,omitempty"`
 	Params    map[string]interface{} `json:"params"`
 	Metrics   map[string]float64     `json:"metrics"`
+	Histogram *Histogram             `json:"histogram,omitempty"`
 }
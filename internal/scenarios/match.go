@@ -0,0 +1,200 @@
+package scenarios
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// controlPlanePrefixes are the paths of MockServer's own API and probes,
+// which request-path scenarios leave alone unless told otherwise.
+var controlPlanePrefixes = []string{
+	"/api/v1/scenarios",
+	"/api/v1/composite",
+	"/api/v1/sessions",
+	"/api/v1/events",
+	"/api/v1/system",
+	"/health",
+	"/ready",
+	"/startup",
+}
+
+// matchParams are the parameters shared by scenarios that act on requests.
+func matchParams() ParamSchema {
+	return ParamSchema{
+		{Name: "path", Type: ParamString, Default: "", Description: "Only requests whose path matches this glob, * within a segment and ** across segments"},
+		{Name: "path_regex", Type: ParamString, Default: "", Description: "Only requests whose path matches this regular expression"},
+		{Name: "method", Type: ParamString, Default: "", Description: "Only these comma separated HTTP methods"},
+		{Name: "headers", Type: ParamString, Default: "", Description: "Only requests with these comma separated Name=value headers, Name alone requires presence"},
+		{Name: "query", Type: ParamString, Default: "", Description: "Only requests with these comma separated name=value query parameters, name alone requires presence"},
+		{Name: "percentage", Type: ParamFloat, Default: 100, Min: bound(0), Max: bound(100), Description: "Percentage of matching requests affected"},
+		{Name: "include_control_plane", Type: ParamBool, Default: false, Description: "Also affect the MockServer API and the health probes"},
+	}
+}
+
+// validateMatch reports the match params that parseRequestMatcher would
+// reject.
+func validateMatch(params map[string]interface{}) []FieldError {
+	if _, err := parseRequestMatcher(params); err != nil {
+		return []FieldError{{Field: "path_regex", Message: err.Error()}}
+	}
+	return nil
+}
+
+// RequestMatcher selects the requests a scenario acts on.
+type RequestMatcher struct {
+	path         *regexp.Regexp
+	methods      []string
+	headers      map[string]string
+	query        map[string]string
+	percentage   float64
+	controlPlane bool
+}
+
+func parseRequestMatcher(params map[string]interface{}) (*RequestMatcher, error) {
+	m := &RequestMatcher{percentage: 100}
+
+	glob, _ := params["path"].(string)
+	expr, _ := params["path_regex"].(string)
+	switch {
+	case glob != "" && expr != "":
+		return nil, fmt.Errorf("path and path_regex are mutually exclusive")
+	case glob != "":
+		m.path = regexp.MustCompile(globToRegexp(glob))
+	case expr != "":
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path_regex: %w", err)
+		}
+		m.path = re
+	}
+
+	if methods, _ := params["method"].(string); methods != "" {
+		for _, method := range strings.Split(methods, ",") {
+			m.methods = append(m.methods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+	}
+	m.headers = parsePairs(params["headers"])
+	m.query = parsePairs(params["query"])
+
+	if p, ok := toFloat(params["percentage"]); ok {
+		m.percentage = p
+	}
+	m.controlPlane, _ = params["include_control_plane"].(bool)
+
+	return m, nil
+}
+
+// Match reports whether the scenario should act on r. Percentage sampling
+// happens last, so it applies to matching requests only. It draws from rng
+// when one is given, so that a seeded scenario stays reproducible.
+func (m *RequestMatcher) Match(r *http.Request, rng *rand.Rand) bool {
+	if !m.controlPlane && isControlPlane(r.URL.Path) {
+		return false
+	}
+	if m.path != nil && !m.path.MatchString(r.URL.Path) {
+		return false
+	}
+	if len(m.methods) > 0 && !contains(m.methods, r.Method) {
+		return false
+	}
+	for name, value := range m.headers {
+		if !pairMatches(r.Header.Values(name), value) {
+			return false
+		}
+	}
+	query := r.URL.Query()
+	for name, value := range m.query {
+		if !pairMatches(query[name], value) {
+			return false
+		}
+	}
+
+	if m.percentage >= 100 {
+		return true
+	}
+	if rng != nil {
+		return rng.Float64()*100 < m.percentage
+	}
+	return rand.Float64()*100 < m.percentage
+}
+
+// pairMatches reports whether one of values equals want, or whether there is
+// any value when want is empty.
+func pairMatches(values []string, want string) bool {
+	if want == "" {
+		return len(values) > 0
+	}
+	return contains(values, want)
+}
+
+func isControlPlane(path string) bool {
+	for _, prefix := range controlPlanePrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePairs(value interface{}) map[string]string {
+	str, _ := value.(string)
+	if str == "" {
+		return nil
+	}
+
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(str, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		pairs[name] = value
+	}
+	return pairs
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// matchScope describes the requests selected by the match params, empty
+// when every business request is affected.
+func matchScope(params map[string]interface{}) string {
+	var parts []string
+	if methods, _ := params["method"].(string); methods != "" {
+		parts = append(parts, methods)
+	}
+	if path, _ := params["path"].(string); path != "" {
+		parts = append(parts, path)
+	} else if expr, _ := params["path_regex"].(string); expr != "" {
+		parts = append(parts, "paths matching "+expr)
+	}
+	if headers, _ := params["headers"].(string); headers != "" {
+		parts = append(parts, "with headers "+headers)
+	}
+	if query, _ := params["query"].(string); query != "" {
+		parts = append(parts, "with query "+query)
+	}
+	if p, ok := toFloat(params["percentage"]); ok && p < 100 {
+		parts = append(parts, fmt.Sprintf("%v%% of traffic", p))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,103 @@
+package scenarios
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{glob: "/api/orders", path: "/api/orders", want: true},
+		{glob: "/api/orders", path: "/api/orders/1", want: false},
+		{glob: "/api/*", path: "/api/orders", want: true},
+		{glob: "/api/*", path: "/api/orders/1", want: false},
+		{glob: "/api/**", path: "/api/orders/1/items", want: true},
+		{glob: "/api/*/items", path: "/api/1/items", want: true},
+		{glob: "/api/*/items", path: "/api/1/2/items", want: false},
+		{glob: "/v?/users", path: "/v2/users", want: true},
+		{glob: "/v?/users", path: "/v10/users", want: false},
+		{glob: "/file.json", path: "/file.json", want: true},
+		{glob: "/file.json", path: "/fileXjson", want: false},
+		{glob: "/a+b", path: "/a+b", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.glob+" "+tt.path, func(t *testing.T) {
+			re := regexp.MustCompile(globToRegexp(tt.glob))
+			if got := re.MatchString(tt.path); got != tt.want {
+				t.Errorf("%s matches %s = %v, want %v", tt.glob, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		method  string
+		target  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "everything", params: map[string]interface{}{}, method: "GET", target: "/api/orders", want: true},
+		{name: "control plane left alone", params: map[string]interface{}{}, method: "GET", target: "/health", want: false},
+		{name: "control plane included", params: map[string]interface{}{"include_control_plane": true}, method: "GET", target: "/api/v1/scenarios/x/status", want: true},
+		{name: "path glob", params: map[string]interface{}{"path": "/api/orders/*"}, method: "GET", target: "/api/orders/42", want: true},
+		{name: "path glob miss", params: map[string]interface{}{"path": "/api/orders/*"}, method: "GET", target: "/api/users/42", want: false},
+		{name: "path regex", params: map[string]interface{}{"path_regex": `^/api/orders/\d+$`}, method: "GET", target: "/api/orders/42", want: true},
+		{name: "path regex miss", params: map[string]interface{}{"path_regex": `^/api/orders/\d+$`}, method: "GET", target: "/api/orders/abc", want: false},
+		{name: "method", params: map[string]interface{}{"method": "post, put"}, method: "PUT", target: "/api/orders", want: true},
+		{name: "method miss", params: map[string]interface{}{"method": "POST"}, method: "GET", target: "/api/orders", want: false},
+		{name: "header value", params: map[string]interface{}{"headers": "X-Tenant=a"}, method: "GET", target: "/", headers: map[string]string{"X-Tenant": "a"}, want: true},
+		{name: "header value miss", params: map[string]interface{}{"headers": "X-Tenant=a"}, method: "GET", target: "/", headers: map[string]string{"X-Tenant": "b"}, want: false},
+		{name: "header presence", params: map[string]interface{}{"headers": "X-Canary"}, method: "GET", target: "/", headers: map[string]string{"X-Canary": "1"}, want: true},
+		{name: "header presence miss", params: map[string]interface{}{"headers": "X-Canary"}, method: "GET", target: "/", want: false},
+		{name: "query", params: map[string]interface{}{"query": "debug=1, region"}, method: "GET", target: "/?debug=1&region=eu", want: true},
+		{name: "query miss", params: map[string]interface{}{"query": "debug=1, region"}, method: "GET", target: "/?debug=1", want: false},
+		{name: "zero percentage", params: map[string]interface{}{"percentage": 0.0}, method: "GET", target: "/", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseRequestMatcher(tt.params)
+			if err != nil {
+				t.Fatalf("parseRequestMatcher error: %v", err)
+			}
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if got := m.Match(r, nil); got != tt.want {
+				t.Errorf("Match(%s %s) = %v, want %v", tt.method, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   int
+	}{
+		{name: "no path", params: map[string]interface{}{"path": "", "path_regex": ""}},
+		{name: "glob", params: map[string]interface{}{"path": "/api/**"}},
+		{name: "regex", params: map[string]interface{}{"path_regex": "^/api/"}},
+		{name: "invalid regex", params: map[string]interface{}{"path_regex": "(["}, want: 1},
+		{name: "both", params: map[string]interface{}{"path": "/api/**", "path_regex": "^/api/"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := validateMatch(tt.params); len(errs) != tt.want {
+				t.Errorf("validateMatch(%v) = %v, want %d errors", tt.params, errs, tt.want)
+			}
+		})
+	}
+}
@@ -2,26 +2,58 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
+
+	"github.com/Z3Labs/MockServer/internal/system"
+)
+
+const (
+	MemoryModeHeap            = "heap"
+	MemoryModeMmap            = "mmap"
+	MemoryModeRetainedObjects = "retained_objects"
+	MemoryModeSawtooth        = "sawtooth"
 )
 
+const mb = 1024 * 1024
+
+// retainedNode is a small pointerful object, the GC has to follow next for
+// every one of them on each cycle.
+type retainedNode struct {
+	next    *retainedNode
+	payload [6]int64
+}
+
 type MemoryLeaker struct {
+	limits       system.Limits
+	mode         string
 	leakedMemory [][]byte
-	leakRateMB   int
+	mappings     [][]byte
+	objects      []*retainedNode
+	leakedMB     int
+	leakRateMB   NumericParam
 	targetMB     int
+	period       time.Duration
+	cycleStart   time.Time
+	cycles       int
 	stopCh       chan struct{}
 	running      atomic.Bool
 	startTime    time.Time
 	params       map[string]interface{}
+	notify       NotifyFunc
 	mu           sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
 
-func NewMemoryLeaker() *MemoryLeaker {
+func NewMemoryLeaker(limits system.Limits) *MemoryLeaker {
 	return &MemoryLeaker{
+		limits:       limits,
 		leakedMemory: make([][]byte, 0),
 		stopCh:       make(chan struct{}),
 		params:       make(map[string]interface{}),
@@ -36,10 +68,104 @@ func (m *MemoryLeaker) Describe() string {
 	return "Continuously leaks memory at specified rate until target is reached"
 }
 
+func (m *MemoryLeaker) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "mode", Type: ParamString, Default: MemoryModeHeap, Enum: []string{MemoryModeHeap, MemoryModeMmap, MemoryModeRetainedObjects, MemoryModeSawtooth}, Description: "heap: Go byte slices; mmap: anonymous mappings outside the Go heap; retained_objects: millions of small pointerful objects; sawtooth: grow then release"},
+		{Name: "target_mb", Type: ParamInt, Default: 1024, Min: bound(1), Description: "Total memory to leak before stopping, in MB"},
+		{Name: "target_fraction_of_memory_limit", Type: ParamFloat, Min: bound(0), Max: bound(1), Description: "Memory to leak as a fraction of the container memory limit, overrides target_mb"},
+		{Name: "leak_rate_mb", Type: ParamInt, Default: 10, Min: bound(0), Rampable: true, Description: "Memory leaked per second, in MB"},
+		{Name: "period_seconds", Type: ParamInt, Default: 0, Min: bound(0), Description: "sawtooth only: release everything after this many seconds, 0 to release when the target is reached"},
+	}
+}
+
+func (m *MemoryLeaker) Validate(params map[string]interface{}) []FieldError {
+	if params["mode"] == MemoryModeMmap && !mmapSupported {
+		return []FieldError{{Field: "mode", Message: fmt.Sprintf("%s is not supported on %s", MemoryModeMmap, runtime.GOOS)}}
+	}
+	return nil
+}
+
+func (m *MemoryLeaker) Diagnosis(params map[string]interface{}) Diagnosis {
+	target := fmt.Sprintf("memory usage growing towards %s MB", paramString(params, "target_mb"))
+	if _, ok := params["target_fraction_of_memory_limit"]; ok {
+		target = fmt.Sprintf("memory usage growing towards %s of the container memory limit", paramString(params, "target_fraction_of_memory_limit"))
+	}
+	rate := fmt.Sprintf("RSS rising by about %s MB per second", paramString(params, "leak_rate_mb"))
+
+	switch params["mode"] {
+	case MemoryModeMmap:
+		return Diagnosis{
+			Category:  "native_memory_growth",
+			RootCause: "native memory growth: anonymous mappings outside the Go heap are never unmapped",
+			Component: "memory",
+			Symptoms: []string{
+				rate,
+				target,
+				"Go heap size stays flat while RSS grows",
+				"eventual OOM kill when the memory limit is reached",
+			},
+		}
+	case MemoryModeRetainedObjects:
+		return Diagnosis{
+			Category:  "gc_thrash",
+			RootCause: "GC thrash: millions of small retained objects make every GC cycle scan a huge heap",
+			Component: "memory",
+			Symptoms: []string{
+				"Go heap object count climbing into the millions",
+				"GC pause time and GC CPU fraction rising",
+				target,
+				"increased request latency during GC cycles",
+			},
+		}
+	case MemoryModeSawtooth:
+		return Diagnosis{
+			Category:  "gc_pressure",
+			RootCause: "memory churn: large allocations are built up and released in bulk periodically",
+			Component: "memory",
+			Symptoms: []string{
+				"sawtooth memory usage: steady growth followed by a sharp drop",
+				target,
+				"GC cycles and heap release spikes at every drop",
+			},
+		}
+	}
+
+	return Diagnosis{
+		Category:  "memory_leak",
+		RootCause: "Go heap leak: allocations are retained and never freed",
+		Component: "memory",
+		Symptoms: []string{
+			rate,
+			target,
+			"Go heap size grows together with RSS",
+			"eventual OOM kill when the memory limit is reached",
+		},
+	}
+}
+
+func (m *MemoryLeaker) SetNotify(notify NotifyFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify = notify
+}
+
 func (m *MemoryLeaker) Start(ctx context.Context, params map[string]interface{}) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	leakRateMB, err := parseNumericParam(params, "leak_rate_mb", 10)
+	if err != nil {
+		return err
+	}
+
+	mode := MemoryModeHeap
+	if s, ok := params["mode"].(string); ok {
+		mode = s
+	}
+	if errs := m.Validate(params); len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+
 	if m.running.Load() {
 		m.stop()
 	}
@@ -47,7 +173,8 @@ func (m *MemoryLeaker) Start(ctx context.Context, params map[string]interface{})
 	m.ctx, m.cancel = context.WithCancel(ctx)
 	m.startTime = time.Now()
 	m.params = params
-	m.leakedMemory = make([][]byte, 0)
+	m.mode = mode
+	m.leakedMB = 0
 
 	targetMB := 1024
 	if t, ok := params["target_mb"].(float64); ok {
@@ -55,48 +182,128 @@ func (m *MemoryLeaker) Start(ctx context.Context, params map[string]interface{})
 	} else if t, ok := params["target_mb"].(int); ok {
 		targetMB = t
 	}
+	if fraction, ok := toFloat(params["target_fraction_of_memory_limit"]); ok {
+		targetMB = int(fraction * m.limits.EffectiveMemoryMB)
+	}
 	m.targetMB = targetMB
+	m.leakRateMB = leakRateMB
 
-	leakRateMB := 10
-	if lr, ok := params["leak_rate_mb"].(float64); ok {
-		leakRateMB = int(lr)
-	} else if lr, ok := params["leak_rate_mb"].(int); ok {
-		leakRateMB = lr
+	m.period = 0
+	if p, ok := toFloat(params["period_seconds"]); ok {
+		m.period = time.Duration(p) * time.Second
 	}
-	m.leakRateMB = leakRateMB
+	m.cycleStart = m.startTime
+	m.cycles = 0
 
 	m.running.Store(true)
-	go m.leakMemory()
+	go m.leakMemory(m.ctx, m.stopCh)
 
 	return nil
 }
 
-func (m *MemoryLeaker) leakMemory() {
+func (m *MemoryLeaker) leakMemory(ctx context.Context, stopCh chan struct{}) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-m.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-m.stopCh:
+		case <-stopCh:
 			return
 		case <-ticker.C:
 			m.mu.Lock()
-			currentMB := len(m.leakedMemory) * m.leakRateMB
-			if currentMB >= m.targetMB {
+			if m.mode == MemoryModeSawtooth && m.sawtoothDue() {
+				m.release()
+				m.cycleStart = time.Now()
+				m.cycles++
+				m.mu.Unlock()
+				// Hand the released memory back to the OS so that the drop
+				// shows up in RSS and not only in the Go heap.
+				debug.FreeOSMemory()
+				continue
+			}
+			if m.leakedMB >= m.targetMB {
 				m.mu.Unlock()
+				if m.mode == MemoryModeSawtooth {
+					continue
+				}
 				return
 			}
 
-			chunk := make([]byte, m.leakRateMB*1024*1024)
-			for i := range chunk {
-				chunk[i] = byte(i % 256)
+			rateMB := int(m.leakRateMB.At(m.startTime))
+			if rateMB <= 0 {
+				m.mu.Unlock()
+				continue
+			}
+			if remaining := m.targetMB - m.leakedMB; rateMB > remaining {
+				rateMB = remaining
+			}
+
+			if err := m.allocate(rateMB); err != nil {
+				m.mu.Unlock()
+				continue
+			}
+			m.leakedMB += rateMB
+			reached := m.leakedMB >= m.targetMB && m.cycles == 0
+			notify := m.notify
+			metrics := map[string]float64{
+				"current_mb": float64(m.leakedMB),
+				"target_mb":  float64(m.targetMB),
 			}
-			m.leakedMemory = append(m.leakedMemory, chunk)
 			m.mu.Unlock()
+
+			if reached && notify != nil {
+				notify(EventTargetReached, metrics)
+			}
+		}
+	}
+}
+
+// sawtoothDue reports whether the current cycle should be released. Callers
+// must hold m.mu.
+func (m *MemoryLeaker) sawtoothDue() bool {
+	if m.period > 0 {
+		return time.Since(m.cycleStart) >= m.period
+	}
+	return m.leakedMB >= m.targetMB
+}
+
+// allocate leaks sizeMB more memory in the current mode. Callers must hold m.mu.
+func (m *MemoryLeaker) allocate(sizeMB int) error {
+	switch m.mode {
+	case MemoryModeMmap:
+		mapping, err := mmapAnonymous(sizeMB * mb)
+		if err != nil {
+			return err
 		}
+		m.mappings = append(m.mappings, mapping)
+	case MemoryModeRetainedObjects:
+		var head *retainedNode
+		count := sizeMB * mb / int(unsafe.Sizeof(retainedNode{}))
+		for i := 0; i < count; i++ {
+			head = &retainedNode{next: head}
+		}
+		m.objects = append(m.objects, head)
+	default:
+		chunk := make([]byte, sizeMB*mb)
+		for i := range chunk {
+			chunk[i] = byte(i % 256)
+		}
+		m.leakedMemory = append(m.leakedMemory, chunk)
+	}
+	return nil
+}
+
+// release frees everything leaked so far. Callers must hold m.mu.
+func (m *MemoryLeaker) release() {
+	for _, mapping := range m.mappings {
+		munmap(mapping)
 	}
+	m.mappings = nil
+	m.objects = nil
+	m.leakedMemory = nil
+	m.leakedMB = 0
 }
 
 func (m *MemoryLeaker) Stop() error {
@@ -116,7 +323,7 @@ func (m *MemoryLeaker) stop() error {
 	}
 	close(m.stopCh)
 	m.stopCh = make(chan struct{})
-	m.leakedMemory = nil
+	m.release()
 
 	return nil
 }
@@ -125,16 +332,39 @@ func (m *MemoryLeaker) Status() ScenarioStatus {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	currentMB := len(m.leakedMemory) * m.leakRateMB
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	mapped := 0
+	for _, mapping := range m.mappings {
+		mapped += len(mapping)
+	}
+
+	var lastPause uint64
+	if stats.NumGC > 0 {
+		lastPause = stats.PauseNs[(stats.NumGC+255)%256]
+	}
 
 	return ScenarioStatus{
 		Running:   m.running.Load(),
 		StartTime: m.startTime,
 		Params:    m.params,
 		Metrics: map[string]float64{
-			"current_mb":   float64(currentMB),
-			"target_mb":    float64(m.targetMB),
-			"leak_rate_mb": float64(m.leakRateMB),
+			"current_mb":         float64(m.leakedMB),
+			"target_mb":          float64(m.targetMB),
+			"leak_rate_mb":       m.leakRateMB.At(m.startTime),
+			"memory_limit_mb":    m.limits.EffectiveMemoryMB,
+			"sawtooth_cycles":    float64(m.cycles),
+			"rss_mb":             float64(system.ResidentMemory()) / mb,
+			"heap_alloc_mb":      float64(stats.HeapAlloc) / mb,
+			"heap_inuse_mb":      float64(stats.HeapInuse) / mb,
+			"heap_sys_mb":        float64(stats.HeapSys) / mb,
+			"heap_objects":       float64(stats.HeapObjects),
+			"gc_count":           float64(stats.NumGC),
+			"gc_pause_last_ms":   float64(lastPause) / 1e6,
+			"gc_pause_total_ms":  float64(stats.PauseTotalNs) / 1e6,
+			"gc_cpu_fraction":    stats.GCCPUFraction,
+			"off_heap_mapped_mb": float64(mapped) / mb,
 		},
 	}
 }
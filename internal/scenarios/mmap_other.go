@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package scenarios
+
+import "errors"
+
+const mmapSupported = false
+
+func mmapAnonymous(size int) ([]byte, error) {
+	return nil, errors.New("mmap is not supported")
+}
+
+func munmap(mapping []byte) {}
@@ -0,0 +1,29 @@
+//go:build linux || darwin
+
+package scenarios
+
+import (
+	"os"
+	"syscall"
+)
+
+const mmapSupported = true
+
+// mmapAnonymous maps size bytes of private anonymous memory and touches every
+// page so that it counts towards RSS. The Go GC never sees this memory.
+func mmapAnonymous(size int) ([]byte, error) {
+	mapping, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(mapping); i += pageSize {
+		mapping[i] = 1
+	}
+	return mapping, nil
+}
+
+func munmap(mapping []byte) {
+	syscall.Munmap(mapping)
+}
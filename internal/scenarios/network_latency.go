@@ -2,20 +2,44 @@ package scenarios
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+const (
+	LatencyFixed     = "fixed"
+	LatencyUniform   = "uniform"
+	LatencyNormal    = "normal"
+	LatencyLogNormal = "lognormal"
+	LatencyPareto    = "pareto"
+	LatencySpike     = "spike"
+)
+
 type NetworkLatency struct {
-	latencyMs int
-	stopCh    chan struct{}
-	running   atomic.Bool
-	startTime time.Time
-	params    map[string]interface{}
-	mu        sync.RWMutex
-	ctx       context.Context
-	cancel    context.CancelFunc
+	latencyMs    NumericParam
+	distribution string
+	maxMs        float64
+	stddevMs     float64
+	sigma        float64
+	alpha        float64
+	spikePercent float64
+	spikeMs      float64
+	rng          *rand.Rand
+	histogram    *Histogram
+	matcher      *RequestMatcher
+	abandoned    atomic.Int64
+	stopCh       chan struct{}
+	running      atomic.Bool
+	startTime    time.Time
+	params       map[string]interface{}
+	mu           sync.RWMutex
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
 func NewNetworkLatency() *NetworkLatency {
@@ -33,10 +57,77 @@ func (n *NetworkLatency) Describe() string {
 	return "Adds specified latency to HTTP requests"
 }
 
+func (n *NetworkLatency) Schema() ParamSchema {
+	return append(ParamSchema{
+		{Name: "latency_ms", Type: ParamInt, Default: 100, Min: bound(0), Rampable: true, Description: "Delay added to every HTTP request, in milliseconds: the minimum for uniform and pareto, the mean for normal, the median for lognormal and the regular delay for spike"},
+		{Name: "distribution", Type: ParamString, Default: LatencyFixed, Enum: []string{LatencyFixed, LatencyUniform, LatencyNormal, LatencyLogNormal, LatencyPareto, LatencySpike}, Description: "How the delay varies between requests"},
+		{Name: "max_latency_ms", Type: ParamInt, Default: 0, Min: bound(0), Description: "Upper bound of the uniform range and cap for every distribution, 0 for no cap"},
+		{Name: "stddev_ms", Type: ParamInt, Default: 20, Min: bound(0), Description: "normal only: standard deviation"},
+		{Name: "sigma", Type: ParamFloat, Default: 0.5, Min: bound(0), Max: bound(5), Description: "lognormal only: standard deviation of the logarithm, larger means a longer tail"},
+		{Name: "alpha", Type: ParamFloat, Default: 1.5, Min: bound(0.1), Max: bound(10), Description: "pareto only: shape, smaller means a heavier tail"},
+		{Name: "spike_percent", Type: ParamFloat, Default: 1, Min: bound(0), Max: bound(100), Description: "spike only: percentage of requests that get spike_ms"},
+		{Name: "spike_ms", Type: ParamInt, Default: 2000, Min: bound(0), Description: "spike only: delay of the spiking requests"},
+		{Name: "seed", Type: ParamInt, Default: 0, Description: "Seed for a reproducible delay sequence, 0 for a random one"},
+	}, matchParams()...)
+}
+
+func (n *NetworkLatency) Validate(params map[string]interface{}) []FieldError {
+	errs := validateMatch(params)
+	if maxMs, _ := toFloat(params["max_latency_ms"]); params["distribution"] == LatencyUniform && maxMs <= 0 {
+		errs = append(errs, FieldError{Field: "max_latency_ms", Message: "required by the uniform distribution"})
+	}
+	return errs
+}
+
+func (n *NetworkLatency) Diagnosis(params map[string]interface{}) Diagnosis {
+	latency := paramString(params, "latency_ms")
+
+	var symptom string
+	switch params["distribution"] {
+	case LatencyUniform:
+		symptom = fmt.Sprintf("request latency increased by %s to %s ms, evenly spread", latency, paramString(params, "max_latency_ms"))
+	case LatencyNormal:
+		symptom = fmt.Sprintf("request latency increased by about %s ms with %s ms of jitter", latency, paramString(params, "stddev_ms"))
+	case LatencyLogNormal:
+		symptom = fmt.Sprintf("median request latency increased by about %s ms with a long right tail", latency)
+	case LatencyPareto:
+		symptom = fmt.Sprintf("request latency increased by at least %s ms with a heavy tail: p99 far above the median", latency)
+	case LatencySpike:
+		symptom = fmt.Sprintf("%s%% of requests delayed by %s ms: p99 latency spikes while the mean barely moves",
+			paramString(params, "spike_percent"), paramString(params, "spike_ms"))
+	default:
+		symptom = fmt.Sprintf("request latency increased by about %s ms", latency)
+	}
+
+	symptoms := []string{symptom, "client timeouts and slower throughput"}
+	if scope := matchScope(params); scope != "" {
+		symptoms = append(symptoms, "only affects "+scope+", other requests keep their normal latency")
+	}
+
+	return Diagnosis{
+		Category:  "network_latency",
+		RootCause: "added latency on inbound HTTP requests",
+		Component: "network",
+		Symptoms:  symptoms,
+	}
+}
+
 func (n *NetworkLatency) Start(ctx context.Context, params map[string]interface{}) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	latencyMs, err := parseNumericParam(params, "latency_ms", 100)
+	if err != nil {
+		return err
+	}
+	if maxMs, _ := toFloat(params["max_latency_ms"]); params["distribution"] == LatencyUniform && maxMs <= 0 {
+		return fmt.Errorf("uniform distribution requires max_latency_ms")
+	}
+	matcher, err := parseRequestMatcher(params)
+	if err != nil {
+		return err
+	}
+
 	if n.running.Load() {
 		n.stop()
 	}
@@ -44,14 +135,34 @@ func (n *NetworkLatency) Start(ctx context.Context, params map[string]interface{
 	n.ctx, n.cancel = context.WithCancel(ctx)
 	n.startTime = time.Now()
 	n.params = params
+	n.latencyMs = latencyMs
 
-	latencyMs := 100
-	if lm, ok := params["latency_ms"].(float64); ok {
-		latencyMs = int(lm)
-	} else if lm, ok := params["latency_ms"].(int); ok {
-		latencyMs = lm
+	n.distribution = LatencyFixed
+	if d, ok := params["distribution"].(string); ok {
+		n.distribution = d
 	}
-	n.latencyMs = latencyMs
+	n.maxMs, n.stddevMs, n.sigma, n.alpha, n.spikePercent, n.spikeMs = 0, 20, 0.5, 1.5, 1, 2000
+	for key, dst := range map[string]*float64{
+		"max_latency_ms": &n.maxMs,
+		"stddev_ms":      &n.stddevMs,
+		"sigma":          &n.sigma,
+		"alpha":          &n.alpha,
+		"spike_percent":  &n.spikePercent,
+		"spike_ms":       &n.spikeMs,
+	} {
+		if v, ok := toFloat(params[key]); ok {
+			*dst = v
+		}
+	}
+
+	seed := time.Now().UnixNano()
+	if v, ok := toFloat(params["seed"]); ok && v != 0 {
+		seed = int64(v)
+	}
+	n.rng = rand.New(rand.NewSource(seed))
+	n.histogram = newLatencyHistogram()
+	n.matcher = matcher
+	n.abandoned.Store(0)
 
 	n.running.Store(true)
 
@@ -83,22 +194,69 @@ func (n *NetworkLatency) Status() ScenarioStatus {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	return ScenarioStatus{
+	status := ScenarioStatus{
 		Running:   n.running.Load(),
 		StartTime: n.startTime,
 		Params:    n.params,
 		Metrics: map[string]float64{
-			"latency_ms": float64(n.latencyMs),
+			"latency_ms": n.latencyMs.At(n.startTime),
 		},
 	}
+	if n.histogram != nil {
+		status.Histogram = n.histogram.copy()
+		status.Metrics["injected_requests"] = float64(n.histogram.Count)
+		status.Metrics["injected_p50_ms"] = n.histogram.Quantile(0.5)
+		status.Metrics["injected_p90_ms"] = n.histogram.Quantile(0.9)
+		status.Metrics["injected_p99_ms"] = n.histogram.Quantile(0.99)
+		status.Metrics["injected_max_ms"] = n.histogram.MaxMs
+		status.Metrics["abandoned_requests"] = float64(n.abandoned.Load())
+	}
+	return status
 }
 
-func (n *NetworkLatency) GetLatency() time.Duration {
-	n.mu.RLock()
-	defer n.mu.RUnlock()
+// GetLatency draws the delay of r from the configured distribution and
+// records it in the histogram, or returns 0 when r does not match.
+func (n *NetworkLatency) GetLatency(r *http.Request) time.Duration {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	if !n.running.Load() {
+	if !n.running.Load() || !n.matcher.Match(r, n.rng) {
 		return 0
 	}
-	return time.Duration(n.latencyMs) * time.Millisecond
+
+	ms := n.sample(n.latencyMs.At(n.startTime))
+	if n.maxMs > 0 {
+		ms = math.Min(ms, n.maxMs)
+	}
+	ms = math.Max(ms, 0)
+	n.histogram.Observe(ms)
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// RecordAbandoned counts a request whose delay was cut short because the
+// client gave up or the server timed out.
+func (n *NetworkLatency) RecordAbandoned() {
+	n.abandoned.Add(1)
+}
+
+func (n *NetworkLatency) sample(base float64) float64 {
+	switch n.distribution {
+	case LatencyUniform:
+		return base + n.rng.Float64()*(n.maxMs-base)
+	case LatencyNormal:
+		return base + n.rng.NormFloat64()*n.stddevMs
+	case LatencyLogNormal:
+		return base * math.Exp(n.rng.NormFloat64()*n.sigma)
+	case LatencyPareto:
+		// Inverse transform sampling, 1-Float64 is in (0, 1].
+		return base / math.Pow(1-n.rng.Float64(), 1/n.alpha)
+	case LatencySpike:
+		if n.rng.Float64()*100 < n.spikePercent {
+			return n.spikeMs
+		}
+		return base
+	default:
+		return base
+	}
 }
@@ -0,0 +1,277 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/system"
+)
+
+const (
+	// oomTick splits the allocation rate into small steps so that the limit
+	// is crossed smoothly rather than in one huge allocation.
+	oomTick = 100 * time.Millisecond
+	// defaultOOMOvershoot bounds the allocation relative to the memory limit.
+	defaultOOMOvershoot = 1.5
+)
+
+// OOMKiller allocates memory past the cgroup limit so that the kernel OOM
+// killer terminates the process with SIGKILL (exit code 137).
+type OOMKiller struct {
+	limits      system.Limits
+	stateDir    string
+	rateMB      int
+	maxMB       int
+	allocated   [][]byte
+	allocatedMB int
+	announced   bool
+	writeMarker bool
+	stopCh      chan struct{}
+	running     atomic.Bool
+	startTime   time.Time
+	params      map[string]interface{}
+	notify      NotifyFunc
+	mu          sync.RWMutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func NewOOMKiller(limits system.Limits, stateDir string) *OOMKiller {
+	return &OOMKiller{
+		limits:   limits,
+		stateDir: stateDir,
+		stopCh:   make(chan struct{}),
+		params:   make(map[string]interface{}),
+	}
+}
+
+func (o *OOMKiller) Name() string {
+	return "oom"
+}
+
+func (o *OOMKiller) Describe() string {
+	return "Allocates memory past the container limit until the kernel OOM killer terminates the process"
+}
+
+func (o *OOMKiller) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "rate_mb", Type: ParamInt, Default: 100, Min: bound(1), Description: "Memory allocated per second, in MB"},
+		{Name: "max_mb", Type: ParamInt, Default: 0, Min: bound(0), Description: "Stop allocating at this many MB, 0 for 1.5 times the cgroup memory limit; required without a limit"},
+		{Name: "write_marker", Type: ParamBool, Default: true, Description: "Write an exit marker so the next run reports the OOM kill via /api/v1/system/last-exit"},
+	}
+}
+
+func (o *OOMKiller) Validate(params map[string]interface{}) []FieldError {
+	maxMB, _ := toFloat(params["max_mb"])
+	limitMB := float64(o.limits.MemoryLimitBytes) / mb
+	switch {
+	case maxMB == 0 && limitMB == 0:
+		return []FieldError{{Field: "max_mb", Message: "required without a cgroup memory limit"}}
+	case maxMB > 0 && limitMB > 0 && maxMB <= limitMB:
+		return []FieldError{{Field: "max_mb", Message: fmt.Sprintf("must exceed the memory limit of %.0f MB to trigger an OOM kill", limitMB)}}
+	}
+	return nil
+}
+
+func (o *OOMKiller) Diagnosis(params map[string]interface{}) Diagnosis {
+	return Diagnosis{
+		Category:  "oom_kill",
+		RootCause: "memory usage exceeded the container memory limit and the kernel OOM killer terminated the process",
+		Component: "memory",
+		Symptoms: []string{
+			fmt.Sprintf("RSS rising by about %s MB per second up to the memory limit", paramString(params, "rate_mb")),
+			"container terminated with exit code 137 (SIGKILL), reason OOMKilled",
+			"restart count increases",
+		},
+	}
+}
+
+func (o *OOMKiller) SetNotify(notify NotifyFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.notify = notify
+}
+
+func (o *OOMKiller) Start(ctx context.Context, params map[string]interface{}) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	rateMB := 100
+	if r, ok := toFloat(params["rate_mb"]); ok {
+		rateMB = int(r)
+	}
+
+	if errs := o.Validate(params); len(errs) > 0 {
+		return &ValidationError{Fields: errs}
+	}
+	maxMB := 0
+	if m, ok := toFloat(params["max_mb"]); ok {
+		maxMB = int(m)
+	}
+	if maxMB == 0 {
+		maxMB = int(float64(o.limits.MemoryLimitBytes) / mb * defaultOOMOvershoot)
+	}
+
+	writeMarker := true
+	if w, ok := params["write_marker"].(bool); ok {
+		writeMarker = w
+	}
+
+	if o.running.Load() {
+		o.stop()
+	}
+
+	o.ctx, o.cancel = context.WithCancel(ctx)
+	o.startTime = time.Now()
+	o.params = params
+	o.rateMB = rateMB
+	o.maxMB = maxMB
+	o.writeMarker = writeMarker
+	o.allocated = nil
+	o.allocatedMB = 0
+	o.announced = false
+
+	o.running.Store(true)
+	go o.allocate(o.ctx, o.stopCh)
+
+	return nil
+}
+
+func (o *OOMKiller) allocate(ctx context.Context, stopCh chan struct{}) {
+	ticker := time.NewTicker(oomTick)
+	defer ticker.Stop()
+
+	ticksPerSecond := int(time.Second / oomTick)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		o.mu.Lock()
+		if ctx.Err() != nil {
+			o.mu.Unlock()
+			return
+		}
+		if o.allocatedMB >= o.maxMB {
+			// The process survived its whole budget, e.g. because of swap or a
+			// misdetected limit, so no OOM kill must be reported on restart.
+			o.finish()
+			o.mu.Unlock()
+			return
+		}
+
+		stepMB := o.rateMB / ticksPerSecond
+		if stepMB < 1 {
+			stepMB = 1
+		}
+		if remaining := o.maxMB - o.allocatedMB; stepMB > remaining {
+			stepMB = remaining
+		}
+
+		// Announce the kill once, right before the limit is crossed, since
+		// nothing can be reported after the SIGKILL. The marker is written
+		// then too, so that a run stopped earlier leaves none behind. Without
+		// a cgroup limit, the host memory is what the OOM killer defends.
+		var notify NotifyFunc
+		var marker *system.ExitMarker
+		limitMB := float64(o.limits.MemoryLimitBytes) / mb
+		rssMB := float64(system.ResidentMemory()) / mb
+		if !o.announced && o.limits.EffectiveMemoryMB > 0 && rssMB+float64(stepMB) >= o.limits.EffectiveMemoryMB {
+			o.announced = true
+			notify = o.notify
+			if o.writeMarker {
+				marker = &system.ExitMarker{
+					Reason:           "oom_kill",
+					Scenario:         o.Name(),
+					ExpectedExitCode: 137,
+					Signal:           "SIGKILL",
+					Details: map[string]interface{}{
+						"memory_limit_bytes": o.limits.MemoryLimitBytes,
+						"max_mb":             o.maxMB,
+					},
+				}
+			}
+		}
+		metrics := map[string]float64{
+			"allocated_mb":    float64(o.allocatedMB),
+			"memory_limit_mb": limitMB,
+		}
+		o.mu.Unlock()
+
+		if marker != nil {
+			system.WriteExitMarker(o.stateDir, *marker)
+		}
+		if notify != nil {
+			notify(EventCrashImminent, metrics)
+		}
+
+		chunk := make([]byte, stepMB*mb)
+		for i := range chunk {
+			chunk[i] = byte(i % 256)
+		}
+
+		o.mu.Lock()
+		if ctx.Err() == nil {
+			o.allocated = append(o.allocated, chunk)
+			o.allocatedMB += stepMB
+		}
+		o.mu.Unlock()
+	}
+}
+
+// finish ends a run that was not killed and releases its memory. Callers
+// must hold o.mu.
+func (o *OOMKiller) finish() {
+	o.running.Store(false)
+	o.allocated = nil
+	o.allocatedMB = 0
+	if o.writeMarker && o.announced {
+		system.ClearExitMarker(o.stateDir)
+	}
+}
+
+func (o *OOMKiller) Stop() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.stop()
+}
+
+func (o *OOMKiller) stop() error {
+	if !o.running.Load() {
+		return nil
+	}
+
+	if o.cancel != nil {
+		o.cancel()
+	}
+	close(o.stopCh)
+	o.stopCh = make(chan struct{})
+	o.finish()
+
+	return nil
+}
+
+func (o *OOMKiller) Status() ScenarioStatus {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return ScenarioStatus{
+		Running:   o.running.Load(),
+		StartTime: o.startTime,
+		Params:    o.params,
+		Metrics: map[string]float64{
+			"allocated_mb":    float64(o.allocatedMB),
+			"max_mb":          float64(o.maxMB),
+			"rate_mb":         float64(o.rateMB),
+			"memory_limit_mb": float64(o.limits.MemoryLimitBytes) / mb,
+			"rss_mb":          float64(system.ResidentMemory()) / mb,
+		},
+	}
+}
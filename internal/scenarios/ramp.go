@@ -0,0 +1,175 @@
+package scenarios
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	RampLinear = "linear"
+	RampStep   = "step"
+	RampExp    = "exp"
+)
+
+// rampKeys are the keys a ramp object may have.
+var rampKeys = []string{"from", "to", "over", "shape", "steps"}
+
+const (
+	defaultRampSteps = 5
+	// expCurvature controls how sharply the exp shape bends towards the end.
+	expCurvature = 5.0
+)
+
+// Ramp moves a numeric parameter from From to To over Over, following Shape.
+type Ramp struct {
+	From  float64
+	To    float64
+	Over  time.Duration
+	Shape string
+	Steps int
+}
+
+func (r *Ramp) At(elapsed time.Duration) float64 {
+	if r.Over <= 0 || elapsed >= r.Over {
+		return r.To
+	}
+	if elapsed <= 0 {
+		return r.From
+	}
+
+	progress := float64(elapsed) / float64(r.Over)
+	switch r.Shape {
+	case RampStep:
+		progress = math.Floor(progress*float64(r.Steps)) / float64(r.Steps)
+	case RampExp:
+		progress = (math.Exp(expCurvature*progress) - 1) / (math.Exp(expCurvature) - 1)
+	}
+
+	return r.From + (r.To-r.From)*progress
+}
+
+// NumericParam is a scenario parameter that is either a constant or a ramp.
+type NumericParam struct {
+	Value float64
+	Ramp  *Ramp
+}
+
+func (p NumericParam) At(start time.Time) float64 {
+	if p.Ramp == nil {
+		return p.Value
+	}
+	return p.Ramp.At(time.Since(start))
+}
+
+// parseNumericParam reads key from params as either a number or a ramp
+// object such as {"from":10,"to":90,"over":"5m","shape":"linear"}.
+func parseNumericParam(params map[string]interface{}, key string, def float64) (NumericParam, error) {
+	raw, ok := params[key]
+	if !ok || raw == nil {
+		return NumericParam{Value: def}, nil
+	}
+
+	if v, ok := toFloat(raw); ok {
+		return NumericParam{Value: v}, nil
+	}
+
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return NumericParam{}, fmt.Errorf("%s must be a number or a ramp object", key)
+	}
+
+	ramp, err := parseRamp(spec)
+	if err != nil {
+		return NumericParam{}, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return NumericParam{Value: ramp.From, Ramp: ramp}, nil
+}
+
+func parseRamp(spec map[string]interface{}) (*Ramp, error) {
+	var unknown []string
+	for key := range spec {
+		if !contains(rampKeys, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown ramp keys %s, expected %s", strings.Join(unknown, ", "), strings.Join(rampKeys, ", "))
+	}
+
+	from, ok := toFloat(spec["from"])
+	if !ok {
+		return nil, fmt.Errorf("ramp requires numeric from")
+	}
+	to, ok := toFloat(spec["to"])
+	if !ok {
+		return nil, fmt.Errorf("ramp requires numeric to")
+	}
+
+	var over time.Duration
+	switch v := spec["over"].(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ramp over %q: %w", v, err)
+		}
+		over = d
+	default:
+		secs, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("ramp requires over as a duration string or seconds")
+		}
+		over = time.Duration(secs * float64(time.Second))
+	}
+	if over <= 0 {
+		return nil, fmt.Errorf("ramp over must be positive")
+	}
+
+	shape := RampLinear
+	if v, ok := spec["shape"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("ramp shape must be a string")
+		}
+		shape = s
+	}
+	if shape != RampLinear && shape != RampStep && shape != RampExp {
+		return nil, fmt.Errorf("unknown ramp shape %q", shape)
+	}
+
+	steps := defaultRampSteps
+	if v, ok := spec["steps"]; ok {
+		s, ok := toFloat(v)
+		if !ok || s < 1 || s != math.Trunc(s) {
+			return nil, fmt.Errorf("ramp steps must be an integer of at least 1")
+		}
+		steps = int(s)
+	}
+
+	return &Ramp{
+		From:  from,
+		To:    to,
+		Over:  over,
+		Shape: shape,
+		Steps: steps,
+	}, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
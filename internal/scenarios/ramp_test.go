@@ -0,0 +1,82 @@
+package scenarios
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRampAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		ramp    Ramp
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "linear start", ramp: Ramp{From: 10, To: 90, Over: time.Minute, Shape: RampLinear}, elapsed: 0, want: 10},
+		{name: "linear middle", ramp: Ramp{From: 10, To: 90, Over: time.Minute, Shape: RampLinear}, elapsed: 30 * time.Second, want: 50},
+		{name: "linear end", ramp: Ramp{From: 10, To: 90, Over: time.Minute, Shape: RampLinear}, elapsed: time.Minute, want: 90},
+		{name: "holds after end", ramp: Ramp{From: 10, To: 90, Over: time.Minute, Shape: RampLinear}, elapsed: time.Hour, want: 90},
+		{name: "before start", ramp: Ramp{From: 10, To: 90, Over: time.Minute, Shape: RampLinear}, elapsed: -time.Second, want: 10},
+		{name: "downwards", ramp: Ramp{From: 100, To: 0, Over: time.Minute, Shape: RampLinear}, elapsed: 15 * time.Second, want: 75},
+		{name: "step holds within a step", ramp: Ramp{From: 0, To: 100, Over: 100 * time.Second, Shape: RampStep, Steps: 4}, elapsed: 49 * time.Second, want: 25},
+		{name: "step jumps at a boundary", ramp: Ramp{From: 0, To: 100, Over: 100 * time.Second, Shape: RampStep, Steps: 4}, elapsed: 50 * time.Second, want: 50},
+		{name: "exp starts slow", ramp: Ramp{From: 0, To: 100, Over: 100 * time.Second, Shape: RampExp}, elapsed: 50 * time.Second, want: 100 * (math.Exp(2.5) - 1) / (math.Exp(5) - 1)},
+		{name: "zero over", ramp: Ramp{From: 10, To: 90, Shape: RampLinear}, elapsed: 0, want: 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ramp.At(tt.elapsed); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("At(%v) = %v, want %v", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    map[string]interface{}
+		want    Ramp
+		wantErr bool
+	}{
+		{
+			name: "duration string",
+			spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m"},
+			want: Ramp{From: 10, To: 90, Over: 5 * time.Minute, Shape: RampLinear, Steps: defaultRampSteps},
+		},
+		{
+			name: "seconds and steps",
+			spec: map[string]interface{}{"from": 0.0, "to": 1.0, "over": 30.0, "shape": RampStep, "steps": 3.0},
+			want: Ramp{From: 0, To: 1, Over: 30 * time.Second, Shape: RampStep, Steps: 3},
+		},
+		{name: "missing from", spec: map[string]interface{}{"to": 90.0, "over": "5m"}, wantErr: true},
+		{name: "non positive over", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": 0.0}, wantErr: true},
+		{name: "invalid over", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "soon"}, wantErr: true},
+		{name: "unknown shape", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "shape": "sine"}, wantErr: true},
+		{name: "non string shape", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "shape": 2.0}, wantErr: true},
+		{name: "zero steps", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "steps": 0.0}, wantErr: true},
+		{name: "fractional steps", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "steps": 2.5}, wantErr: true},
+		{name: "non numeric steps", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "steps": "3"}, wantErr: true},
+		{name: "unknown key", spec: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "5m", "shap": "exp"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ramp, err := parseRamp(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseRamp(%v) = %+v, want an error", tt.spec, ramp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRamp(%v) error: %v", tt.spec, err)
+			}
+			if *ramp != tt.want {
+				t.Errorf("parseRamp(%v) = %+v, want %+v", tt.spec, *ramp, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package scenarios
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ParamInt    = "int"
+	ParamFloat  = "float"
+	ParamString = "string"
+	ParamBool   = "bool"
+)
+
+// ParamSpec describes one parameter accepted by a scenario.
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"`
+	Rampable    bool        `json:"rampable,omitempty"`
+	Description string      `json:"description"`
+}
+
+type ParamSchema []ParamSpec
+
+type FieldError struct {
+	Scenario string `json:"scenario,omitempty"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+// ValidationError reports every invalid field of a request at once.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		field := f.Field
+		if f.Scenario != "" {
+			field = f.Scenario + "." + field
+		}
+		msgs = append(msgs, fmt.Sprintf("%s: %s", field, f.Message))
+	}
+	return "invalid scenario parameters: " + strings.Join(msgs, "; ")
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func (s ParamSchema) spec(name string) (ParamSpec, bool) {
+	for _, spec := range s {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return ParamSpec{}, false
+}
+
+// Normalize validates params against the schema and returns a copy with
+// numbers converted to float64 and defaults filled in for missing keys.
+func (s ParamSchema) Normalize(scenario string, params map[string]interface{}) (map[string]interface{}, []FieldError) {
+	var errs []FieldError
+	normalized := make(map[string]interface{}, len(s))
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		spec, ok := s.spec(key)
+		if !ok {
+			errs = append(errs, FieldError{Scenario: scenario, Field: key, Message: "unknown parameter"})
+			continue
+		}
+
+		value, err := spec.normalize(params[key])
+		if err != nil {
+			errs = append(errs, FieldError{Scenario: scenario, Field: key, Message: err.Error()})
+			continue
+		}
+		normalized[key] = value
+	}
+
+	// Defaults are numbers as decoded from JSON, like the values passed in.
+	for _, spec := range s {
+		if _, ok := normalized[spec.Name]; ok || spec.Default == nil {
+			continue
+		}
+		if f, ok := toFloat(spec.Default); ok {
+			normalized[spec.Name] = f
+		} else {
+			normalized[spec.Name] = spec.Default
+		}
+	}
+
+	return normalized, errs
+}
+
+func (p ParamSpec) normalize(value interface{}) (interface{}, error) {
+	if obj, ok := value.(map[string]interface{}); ok && p.Rampable {
+		ramp, err := parseRamp(obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkRange(ramp.From); err != nil {
+			return nil, fmt.Errorf("ramp from %w", err)
+		}
+		if err := p.checkRange(ramp.To); err != nil {
+			return nil, fmt.Errorf("ramp to %w", err)
+		}
+
+		normalized := map[string]interface{}{
+			"from":  ramp.From,
+			"to":    ramp.To,
+			"over":  ramp.Over.String(),
+			"shape": ramp.Shape,
+		}
+		if ramp.Shape == RampStep {
+			normalized["steps"] = float64(ramp.Steps)
+		}
+		return normalized, nil
+	}
+
+	switch p.Type {
+	case ParamInt, ParamFloat:
+		n, ok := toFloat(value)
+		if !ok {
+			if p.Rampable {
+				return nil, fmt.Errorf("expected %s or ramp object, got %s", p.Type, typeName(value))
+			}
+			return nil, fmt.Errorf("expected %s, got %s", p.Type, typeName(value))
+		}
+		if p.Type == ParamInt && n != math.Trunc(n) {
+			return nil, fmt.Errorf("expected int, got %v", n)
+		}
+		if err := p.checkRange(n); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case ParamString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %s", typeName(value))
+		}
+		if len(p.Enum) > 0 && !contains(p.Enum, str) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(p.Enum, ", "))
+		}
+		if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(str) {
+			return nil, fmt.Errorf("must match %s", p.Pattern)
+		}
+		return str, nil
+	case ParamBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %s", typeName(value))
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+func (p ParamSpec) checkRange(n float64) error {
+	if p.Min != nil && n < *p.Min {
+		return fmt.Errorf("must be >= %v", *p.Min)
+	}
+	if p.Max != nil && n > *p.Max {
+		return fmt.Errorf("must be <= %v", *p.Max)
+	}
+	return nil
+}
+
+// paramString formats a normalized parameter for human readable output.
+func paramString(params map[string]interface{}, key string) string {
+	value, ok := params[key]
+	if !ok {
+		return "?"
+	}
+	if ramp, ok := value.(map[string]interface{}); ok {
+		return fmt.Sprintf("%v-%v", ramp["from"], ramp["to"])
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		if _, ok := toFloat(value); ok {
+			return "number"
+		}
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
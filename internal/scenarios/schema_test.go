@@ -0,0 +1,106 @@
+package scenarios
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testSchema() ParamSchema {
+	return ParamSchema{
+		{Name: "target_percent", Type: ParamFloat, Default: 50, Min: bound(0), Max: bound(100), Rampable: true},
+		{Name: "count", Type: ParamInt, Default: 1, Min: bound(1)},
+		{Name: "mode", Type: ParamString, Default: "fast", Enum: []string{"fast", "slow"}},
+		{Name: "pattern", Type: ParamString, Default: "UD", Pattern: "^[UD]+$"},
+		{Name: "verbose", Type: ParamBool, Default: false},
+	}
+}
+
+func TestParamSchemaNormalize(t *testing.T) {
+	params := map[string]interface{}{
+		"target_percent": 80,
+		"mode":           "slow",
+	}
+
+	normalized, errs := testSchema().Normalize("test", params)
+	if len(errs) > 0 {
+		t.Fatalf("Normalize errors: %v", errs)
+	}
+
+	want := map[string]interface{}{
+		"target_percent": 80.0,
+		"count":          1.0,
+		"mode":           "slow",
+		"pattern":        "UD",
+		"verbose":        false,
+	}
+	if !reflect.DeepEqual(normalized, want) {
+		t.Errorf("Normalize = %v, want %v", normalized, want)
+	}
+	if _, ok := params["count"]; ok {
+		t.Error("Normalize modified its input")
+	}
+}
+
+func TestParamSchemaNormalizeRamp(t *testing.T) {
+	normalized, errs := testSchema().Normalize("test", map[string]interface{}{
+		"target_percent": map[string]interface{}{"from": 10.0, "to": 90.0, "over": 60.0, "shape": RampStep, "steps": 3.0},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("Normalize errors: %v", errs)
+	}
+
+	want := map[string]interface{}{"from": 10.0, "to": 90.0, "over": "1m0s", "shape": RampStep, "steps": 3.0}
+	if got := normalized["target_percent"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("target_percent = %v, want %v", got, want)
+	}
+}
+
+func TestParamSchemaNormalizeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		param string
+		value interface{}
+	}{
+		{name: "unknown parameter", param: "colour", value: "red"},
+		{name: "wrong type", param: "count", value: "two"},
+		{name: "fractional int", param: "count", value: 1.5},
+		{name: "below min", param: "count", value: 0.0},
+		{name: "above max", param: "target_percent", value: 101.0},
+		{name: "not in enum", param: "mode", value: "medium"},
+		{name: "pattern mismatch", param: "pattern", value: "UXD"},
+		{name: "bool as string", param: "verbose", value: "true"},
+		{name: "ramp on a constant param", param: "count", value: map[string]interface{}{"from": 1.0, "to": 5.0, "over": "1m"}},
+		{name: "ramp out of range", param: "target_percent", value: map[string]interface{}{"from": 10.0, "to": 150.0, "over": "1m"}},
+		{name: "ramp with a non string shape", param: "target_percent", value: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "1m", "shape": 1.0}},
+		{name: "ramp with invalid steps", param: "target_percent", value: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "1m", "shape": RampStep, "steps": 0.0}},
+		{name: "ramp with unknown key", param: "target_percent", value: map[string]interface{}{"from": 10.0, "to": 90.0, "over": "1m", "duration": 5.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := testSchema().Normalize("test", map[string]interface{}{tt.param: tt.value})
+			if len(errs) != 1 {
+				t.Fatalf("Normalize(%s=%v) errors = %v, want one", tt.param, tt.value, errs)
+			}
+			if errs[0].Scenario != "test" || errs[0].Field != tt.param {
+				t.Errorf("error on %s.%s, want test.%s", errs[0].Scenario, errs[0].Field, tt.param)
+			}
+		})
+	}
+}
+
+func TestParamSchemaNormalizeReportsEveryField(t *testing.T) {
+	_, errs := testSchema().Normalize("test", map[string]interface{}{
+		"mode":    "medium",
+		"count":   -1.0,
+		"verbose": 1.0,
+	})
+
+	fields := make([]string, 0, len(errs))
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	if want := []string{"count", "mode", "verbose"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("error fields = %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,152 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/lifecycle"
+)
+
+const (
+	ShutdownIgnore   = "ignore"
+	ShutdownHang     = "hang"
+	ShutdownDrop     = "drop"
+	ShutdownNotReady = "not_ready"
+)
+
+// ShutdownMisbehavior changes how the process reacts to SIGTERM. It does
+// nothing until a signal arrives.
+type ShutdownMisbehavior struct {
+	mode        string
+	hangSeconds int
+	signals     atomic.Int64
+	running     atomic.Bool
+	startTime   time.Time
+	params      map[string]interface{}
+	mu          sync.RWMutex
+}
+
+func NewShutdownMisbehavior() *ShutdownMisbehavior {
+	return &ShutdownMisbehavior{
+		params: make(map[string]interface{}),
+	}
+}
+
+func (s *ShutdownMisbehavior) Name() string {
+	return "shutdown"
+}
+
+func (s *ShutdownMisbehavior) Describe() string {
+	return "Misbehaves on SIGTERM: ignores it, hangs, drops in-flight requests or stays unready while serving"
+}
+
+func (s *ShutdownMisbehavior) Schema() ParamSchema {
+	return ParamSchema{
+		{Name: "mode", Type: ParamString, Default: ShutdownHang, Enum: []string{ShutdownIgnore, ShutdownHang, ShutdownDrop, ShutdownNotReady}, Description: "Reaction to SIGTERM"},
+		{Name: "hang_seconds", Type: ParamInt, Default: 60, Min: bound(1), Description: "hang only: seconds to keep running after SIGTERM before exiting"},
+	}
+}
+
+func (s *ShutdownMisbehavior) Diagnosis(params map[string]interface{}) Diagnosis {
+	diagnosis := Diagnosis{
+		Category:  "shutdown_misbehavior",
+		Component: "process",
+	}
+
+	switch params["mode"] {
+	case ShutdownIgnore:
+		diagnosis.RootCause = "SIGTERM is ignored"
+		diagnosis.Symptoms = []string{
+			"pod stays Terminating and ready for the whole termination grace period",
+			"process killed with SIGKILL (exit code 137) when the grace period ends",
+		}
+	case ShutdownDrop:
+		diagnosis.RootCause = "in-flight requests are dropped on SIGTERM instead of drained"
+		diagnosis.Symptoms = []string{
+			"connection reset and EOF errors on clients during rollouts",
+			"process exits immediately after SIGTERM",
+		}
+	case ShutdownNotReady:
+		diagnosis.RootCause = "process never exits after SIGTERM"
+		diagnosis.Symptoms = []string{
+			"readiness probe fails after SIGTERM while the process keeps serving traffic",
+			"process killed with SIGKILL (exit code 137) when the grace period ends",
+		}
+	default:
+		diagnosis.RootCause = "graceful shutdown hangs"
+		diagnosis.Symptoms = []string{
+			fmt.Sprintf("process keeps running for %s seconds after SIGTERM", paramString(params, "hang_seconds")),
+			"readiness probe fails after SIGTERM",
+			"process killed with SIGKILL (exit code 137) if the hang outlasts the grace period",
+		}
+	}
+
+	return diagnosis
+}
+
+func (s *ShutdownMisbehavior) Start(ctx context.Context, params map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.startTime = time.Now()
+	s.params = params
+
+	s.mode = ShutdownHang
+	if mode, ok := params["mode"].(string); ok {
+		s.mode = mode
+	}
+	s.hangSeconds = 60
+	if n, ok := toFloat(params["hang_seconds"]); ok {
+		s.hangSeconds = int(n)
+	}
+	s.signals.Store(0)
+
+	s.running.Store(true)
+	return nil
+}
+
+// OnTerminate implements lifecycle.Hook.
+func (s *ShutdownMisbehavior) OnTerminate(sig os.Signal) (lifecycle.Action, time.Duration) {
+	if !s.running.Load() {
+		return lifecycle.Graceful, 0
+	}
+	s.signals.Add(1)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch s.mode {
+	case ShutdownIgnore:
+		return lifecycle.Ignore, 0
+	case ShutdownDrop:
+		return lifecycle.Drop, 0
+	case ShutdownNotReady:
+		return lifecycle.NotReady, 0
+	default:
+		return lifecycle.Hang, time.Duration(s.hangSeconds) * time.Second
+	}
+}
+
+func (s *ShutdownMisbehavior) Stop() error {
+	s.running.Store(false)
+	return nil
+}
+
+func (s *ShutdownMisbehavior) Status() ScenarioStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return ScenarioStatus{
+		Running:   s.running.Load(),
+		StartTime: s.startTime,
+		Params:    s.params,
+		Metrics: map[string]float64{
+			"hang_seconds":     float64(s.hangSeconds),
+			"signals_received": float64(s.signals.Load()),
+		},
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package scenarios
+
+// raiseSignal is a no-op where signals are not available, the caller falls
+// back to os.Exit.
+func raiseSignal(crashType string) {}
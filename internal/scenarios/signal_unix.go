@@ -0,0 +1,17 @@
+//go:build unix
+
+package scenarios
+
+import (
+	"os"
+	"syscall"
+)
+
+// raiseSignal sends the signal named by crashType to the process itself.
+func raiseSignal(crashType string) {
+	sig := syscall.SIGKILL
+	if crashType == CrashSIGABRT {
+		sig = syscall.SIGABRT
+	}
+	syscall.Kill(os.Getpid(), sig)
+}
@@ -2,17 +2,42 @@ package svc
 
 import (
 	"github.com/Z3Labs/MockServer/internal/config"
+	"github.com/Z3Labs/MockServer/internal/health"
+	"github.com/Z3Labs/MockServer/internal/lifecycle"
 	"github.com/Z3Labs/MockServer/internal/manager"
+	"github.com/Z3Labs/MockServer/internal/system"
+	"github.com/Z3Labs/MockServer/internal/webhook"
 )
 
 type ServiceContext struct {
 	Config          config.Config
 	ScenarioManager *manager.ScenarioManager
+	Limits          system.Limits
+	LastExit        system.LastExit
+	Lifecycle       *lifecycle.Lifecycle
+	Health          *health.Model
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
+	limits := system.DetectLimits(c.System.CgroupRoot)
+	lastExit := system.LoadLastExit(c.System.StateDir)
+	scenarioManager := manager.NewScenarioManager(c, limits)
+	webhook.NewDispatcher(c.Webhooks).Attach(scenarioManager.Events())
+	scenarioManager.Run()
+
+	lc := lifecycle.New()
+	if scenario, ok := scenarioManager.GetScenario("shutdown"); ok {
+		if hook, ok := scenario.(lifecycle.Hook); ok {
+			lc.AddHook(hook)
+		}
+	}
+
 	return &ServiceContext{
 		Config:          c,
-		ScenarioManager: manager.NewScenarioManager(),
+		ScenarioManager: scenarioManager,
+		Limits:          limits,
+		LastExit:        lastExit,
+		Lifecycle:       lc,
+		Health:          health.NewModel(c.Health),
 	}
 }
@@ -0,0 +1,106 @@
+package system
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DefaultStateDir = "/tmp/mockserver"
+
+	exitMarkerFile = "exit-marker.json"
+)
+
+// ExitMarker is written by a scenario that is about to take the process down
+// in a way it cannot report itself, such as an OOM kill.
+type ExitMarker struct {
+	Reason           string                 `json:"reason"`
+	Scenario         string                 `json:"scenario,omitempty"`
+	ExpectedExitCode int                    `json:"expected_exit_code,omitempty"`
+	Signal           string                 `json:"signal,omitempty"`
+	Pid              int                    `json:"pid"`
+	Time             time.Time              `json:"time"`
+	Details          map[string]interface{} `json:"details,omitempty"`
+}
+
+// LastExit describes how the previous run of the process ended, as far as
+// it left a marker behind.
+type LastExit struct {
+	Known   bool        `json:"known"`
+	Message string      `json:"message"`
+	Marker  *ExitMarker `json:"marker,omitempty"`
+}
+
+// WriteExitMarker records how the process is expected to die. The file is
+// synced so that it survives an immediate SIGKILL.
+func WriteExitMarker(dir string, marker ExitMarker) error {
+	if dir == "" {
+		dir = DefaultStateDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	marker.Pid = os.Getpid()
+	if marker.Time.IsZero() {
+		marker.Time = time.Now()
+	}
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, exitMarkerFile))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ClearExitMarker removes the marker once the fault it announced is called off.
+func ClearExitMarker(dir string) {
+	if dir == "" {
+		dir = DefaultStateDir
+	}
+	os.Remove(filepath.Join(dir, exitMarkerFile))
+}
+
+// LoadLastExit consumes the marker left by the previous run, so that it is
+// reported by exactly one restart.
+func LoadLastExit(dir string) LastExit {
+	if dir == "" {
+		dir = DefaultStateDir
+	}
+	file := filepath.Join(dir, exitMarkerFile)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return LastExit{Message: "no exit marker left by the previous run"}
+	}
+	os.Remove(file)
+
+	var marker ExitMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return LastExit{Message: "unreadable exit marker: " + err.Error()}
+	}
+
+	message := "previous run ended by " + marker.Reason
+	if marker.Scenario != "" {
+		message += " from scenario " + marker.Scenario
+	}
+	return LastExit{
+		Known:   true,
+		Message: message,
+		Marker:  &marker,
+	}
+}
@@ -0,0 +1,195 @@
+package system
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultCgroupRoot = "/sys/fs/cgroup"
+
+	procSelfCgroup = "/proc/self/cgroup"
+	procMeminfo    = "/proc/meminfo"
+
+	// cgroup v1 reports "no limit" as a page aligned value close to MaxInt64.
+	unlimitedV1 = int64(1) << 62
+)
+
+// Limits are the resources available to the process, as detected at startup.
+// CPU and memory limits are zero when the cgroup does not set one.
+type Limits struct {
+	CgroupVersion     int     `json:"cgroup_version"`
+	CPULimitCores     float64 `json:"cpu_limit_cores,omitempty"`
+	MemoryLimitBytes  int64   `json:"memory_limit_bytes,omitempty"`
+	NumCPU            int     `json:"num_cpu"`
+	GoMaxProcs        int     `json:"gomaxprocs"`
+	HostMemoryBytes   int64   `json:"host_memory_bytes,omitempty"`
+	EffectiveCPUCores float64 `json:"effective_cpu_cores"`
+	EffectiveMemoryMB float64 `json:"effective_memory_mb,omitempty"`
+}
+
+// DetectLimits reads the cgroup v1 or v2 CPU and memory limits below root,
+// which is normally /sys/fs/cgroup but can point at fake files.
+func DetectLimits(root string) Limits {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+
+	limits := Limits{
+		NumCPU:          runtime.NumCPU(),
+		GoMaxProcs:      runtime.GOMAXPROCS(0),
+		HostMemoryBytes: hostMemory(),
+	}
+
+	paths := cgroupPaths()
+	if fileExists(filepath.Join(root, "cgroup.controllers")) {
+		limits.CgroupVersion = 2
+		dir := cgroupDir(root, paths[""], "cpu.max")
+		limits.CPULimitCores = readCPUMax(filepath.Join(dir, "cpu.max"))
+		dir = cgroupDir(root, paths[""], "memory.max")
+		limits.MemoryLimitBytes = readMemoryLimit(filepath.Join(dir, "memory.max"))
+	} else if fileExists(filepath.Join(root, "memory")) || fileExists(filepath.Join(root, "cpu")) {
+		limits.CgroupVersion = 1
+		dir := cgroupDir(filepath.Join(root, "cpu"), paths["cpu"], "cpu.cfs_quota_us")
+		limits.CPULimitCores = readCFSQuota(dir)
+		dir = cgroupDir(filepath.Join(root, "memory"), paths["memory"], "memory.limit_in_bytes")
+		limits.MemoryLimitBytes = readMemoryLimit(filepath.Join(dir, "memory.limit_in_bytes"))
+	}
+
+	limits.EffectiveCPUCores = float64(limits.NumCPU)
+	if limits.CPULimitCores > 0 && limits.CPULimitCores < limits.EffectiveCPUCores {
+		limits.EffectiveCPUCores = limits.CPULimitCores
+	}
+
+	memory := limits.HostMemoryBytes
+	if limits.MemoryLimitBytes > 0 && (memory == 0 || limits.MemoryLimitBytes < memory) {
+		memory = limits.MemoryLimitBytes
+	}
+	limits.EffectiveMemoryMB = float64(memory) / (1 << 20)
+
+	return limits
+}
+
+// Cores returns how many cores a CPU limit spans, at least one and at most
+// the number of CPUs.
+func (l Limits) Cores() int {
+	cores := int(math.Ceil(l.EffectiveCPUCores))
+	if cores < 1 {
+		cores = 1
+	}
+	if l.NumCPU > 0 && cores > l.NumCPU {
+		cores = l.NumCPU
+	}
+	return cores
+}
+
+// cgroupPaths maps each v1 controller, and "" for v2, to the cgroup of the
+// process as listed in /proc/self/cgroup.
+func cgroupPaths() map[string]string {
+	paths := make(map[string]string)
+
+	f, err := os.Open(procSelfCgroup)
+	if err != nil {
+		return paths
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+
+	return paths
+}
+
+// cgroupDir prefers the process's own cgroup below base and falls back to
+// base itself, which is what a container with a cgroup namespace sees.
+func cgroupDir(base, path, file string) string {
+	if path != "" && path != "/" {
+		dir := filepath.Join(base, path)
+		if fileExists(filepath.Join(dir, file)) {
+			return dir
+		}
+	}
+	return base
+}
+
+// readCPUMax parses a cgroup v2 cpu.max such as "200000 100000" or "max 100000".
+func readCPUMax(file string) float64 {
+	fields := strings.Fields(readString(file))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+	quota, err1 := strconv.ParseFloat(fields[0], 64)
+	period, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return 0
+	}
+	return quota / period
+}
+
+func readCFSQuota(dir string) float64 {
+	quota, err1 := strconv.ParseFloat(readString(filepath.Join(dir, "cpu.cfs_quota_us")), 64)
+	period, err2 := strconv.ParseFloat(readString(filepath.Join(dir, "cpu.cfs_period_us")), 64)
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return 0
+	}
+	return quota / period
+}
+
+func readMemoryLimit(file string) int64 {
+	value := readString(file)
+	if value == "" || value == "max" {
+		return 0
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 || limit >= unlimitedV1 {
+		return 0
+	}
+	return limit
+}
+
+func hostMemory() int64 {
+	f, err := os.Open(procMeminfo)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+func readString(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,176 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDetectLimitsV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		cores  float64
+		memory int64
+	}{
+		{
+			name: "limited",
+			files: map[string]string{
+				"cpu.max":    "150000 100000",
+				"memory.max": "536870912",
+			},
+			cores:  1.5,
+			memory: 512 << 20,
+		},
+		{
+			name: "max",
+			files: map[string]string{
+				"cpu.max":    "max 100000",
+				"memory.max": "max",
+			},
+		},
+		{
+			name: "missing files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{"cgroup.controllers": "cpu memory"})
+			writeFiles(t, root, tt.files)
+
+			limits := DetectLimits(root)
+			if limits.CgroupVersion != 2 {
+				t.Errorf("CgroupVersion = %d, want 2", limits.CgroupVersion)
+			}
+			if limits.CPULimitCores != tt.cores {
+				t.Errorf("CPULimitCores = %v, want %v", limits.CPULimitCores, tt.cores)
+			}
+			if limits.MemoryLimitBytes != tt.memory {
+				t.Errorf("MemoryLimitBytes = %d, want %d", limits.MemoryLimitBytes, tt.memory)
+			}
+		})
+	}
+}
+
+func TestDetectLimitsV1(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		cores  float64
+		memory int64
+	}{
+		{
+			name: "limited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "200000",
+				"cpu/cpu.cfs_period_us":        "100000",
+				"memory/memory.limit_in_bytes": "268435456",
+			},
+			cores:  2,
+			memory: 256 << 20,
+		},
+		{
+			name: "unlimited",
+			files: map[string]string{
+				"cpu/cpu.cfs_quota_us":         "-1",
+				"cpu/cpu.cfs_period_us":        "100000",
+				"memory/memory.limit_in_bytes": "9223372036854771712",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			limits := DetectLimits(root)
+			if limits.CgroupVersion != 1 {
+				t.Errorf("CgroupVersion = %d, want 1", limits.CgroupVersion)
+			}
+			if limits.CPULimitCores != tt.cores {
+				t.Errorf("CPULimitCores = %v, want %v", limits.CPULimitCores, tt.cores)
+			}
+			if limits.MemoryLimitBytes != tt.memory {
+				t.Errorf("MemoryLimitBytes = %d, want %d", limits.MemoryLimitBytes, tt.memory)
+			}
+		})
+	}
+}
+
+func TestDetectLimitsNone(t *testing.T) {
+	limits := DetectLimits(t.TempDir())
+	if limits.CgroupVersion != 0 || limits.CPULimitCores != 0 || limits.MemoryLimitBytes != 0 {
+		t.Errorf("DetectLimits = %+v, want no cgroup limits", limits)
+	}
+	if limits.EffectiveCPUCores != float64(limits.NumCPU) {
+		t.Errorf("EffectiveCPUCores = %v, want NumCPU %d", limits.EffectiveCPUCores, limits.NumCPU)
+	}
+}
+
+func TestCgroupDirNested(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"cpu.max":                          "max 100000",
+		"kubepods.slice/pod-a/ctr/cpu.max": "50000 100000",
+	})
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "nested", path: "/kubepods.slice/pod-a/ctr", want: filepath.Join(root, "kubepods.slice/pod-a/ctr")},
+		{name: "root", path: "/", want: root},
+		{name: "not mounted", path: "/other.slice", want: root},
+		{name: "unknown", path: "", want: root},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := cgroupDir(root, tt.path, "cpu.max")
+			if dir != tt.want {
+				t.Errorf("cgroupDir = %s, want %s", dir, tt.want)
+			}
+		})
+	}
+
+	if cores := readCPUMax(filepath.Join(cgroupDir(root, "/kubepods.slice/pod-a/ctr", "cpu.max"), "cpu.max")); cores != 0.5 {
+		t.Errorf("nested cpu.max = %v cores, want 0.5", cores)
+	}
+}
+
+func TestCores(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		want   int
+	}{
+		{name: "fraction rounds up", limits: Limits{NumCPU: 8, EffectiveCPUCores: 1.5}, want: 2},
+		{name: "at least one", limits: Limits{NumCPU: 8, EffectiveCPUCores: 0.1}, want: 1},
+		{name: "at most NumCPU", limits: Limits{NumCPU: 4, EffectiveCPUCores: 6}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.Cores(); got != tt.want {
+				t.Errorf("Cores() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
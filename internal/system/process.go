@@ -0,0 +1,23 @@
+package system
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const procSelfStatm = "/proc/self/statm"
+
+// ResidentMemory returns the resident set size of the process in bytes, or 0
+// where /proc is not available.
+func ResidentMemory() int64 {
+	fields := strings.Fields(readString(procSelfStatm))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * int64(os.Getpagesize())
+}
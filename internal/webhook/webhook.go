@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/config"
+	"github.com/Z3Labs/MockServer/internal/manager"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	HeaderEvent     = "X-MockServer-Event"
+	HeaderDelivery  = "X-MockServer-Delivery"
+	HeaderSignature = "X-MockServer-Signature"
+
+	queueSize = 100
+	// crashDeadline bounds how long a crash waits for its notifications.
+	crashDeadline = 5 * time.Second
+)
+
+// Dispatcher POSTs manager events to the configured webhook targets.
+type Dispatcher struct {
+	targets []*target
+}
+
+type target struct {
+	conf   config.WebhookConf
+	events map[string]bool
+	client *http.Client
+	queue  chan delivery
+}
+
+type delivery struct {
+	event manager.Event
+	body  []byte
+}
+
+func NewDispatcher(confs []config.WebhookConf) *Dispatcher {
+	d := &Dispatcher{}
+	for _, conf := range confs {
+		t := &target{
+			conf:   conf,
+			client: &http.Client{Timeout: time.Duration(conf.Timeout) * time.Second},
+			queue:  make(chan delivery, queueSize),
+		}
+		if len(conf.Events) > 0 {
+			t.events = make(map[string]bool)
+			for _, event := range conf.Events {
+				t.events[event] = true
+			}
+		}
+		d.targets = append(d.targets, t)
+		go t.run()
+	}
+	return d
+}
+
+// Attach delivers every event published on bus from now on.
+func (d *Dispatcher) Attach(bus *manager.EventBus) {
+	if len(d.targets) == 0 {
+		return
+	}
+	bus.OnPublish(d.dispatch)
+}
+
+// dispatch queues the event for every interested target. A crash is sent
+// synchronously because the process exits as soon as the publisher returns.
+func (d *Dispatcher) dispatch(event manager.Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logx.Errorf("encode webhook event %d: %v", event.Id, err)
+		return
+	}
+
+	if event.Type == manager.EventScenarioCrashImminent {
+		ctx, cancel := context.WithTimeout(context.Background(), crashDeadline)
+		defer cancel()
+
+		var wg sync.WaitGroup
+		for _, t := range d.targets {
+			if t.wants(event.Type) {
+				wg.Add(1)
+				go func(t *target) {
+					defer wg.Done()
+					t.deliver(ctx, delivery{event: event, body: body})
+				}(t)
+			}
+		}
+		wg.Wait()
+		return
+	}
+
+	for _, t := range d.targets {
+		if !t.wants(event.Type) {
+			continue
+		}
+		select {
+		case t.queue <- delivery{event: event, body: body}:
+		default:
+			logx.Errorf("webhook %s queue full, dropping event %d (%s)", t.conf.Url, event.Id, event.Type)
+		}
+	}
+}
+
+func (t *target) wants(eventType string) bool {
+	return t.events == nil || t.events[eventType]
+}
+
+func (t *target) run() {
+	for d := range t.queue {
+		t.deliver(context.Background(), d)
+	}
+}
+
+// deliver retries failed attempts with exponential backoff. Client errors
+// other than 429 are not retried.
+func (t *target) deliver(ctx context.Context, d delivery) {
+	backoff := time.Duration(t.conf.RetryInterval) * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		retry, err := t.post(ctx, d)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= t.conf.MaxRetries {
+			logx.Errorf("webhook %s event %d (%s) failed after %d attempts: %v",
+				t.conf.Url, d.event.Id, d.event.Type, attempt+1, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			logx.Errorf("webhook %s event %d (%s) abandoned: %v", t.conf.Url, d.event.Id, d.event.Type, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (t *target) post(ctx context.Context, d delivery) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.conf.Url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event.Type)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.event.Id, 10))
+	if t.conf.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(t.conf.Secret, d.body))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the signature header value for body: the hex encoded
+// HMAC-SHA256 of the body keyed with secret, prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Z3Labs/MockServer/internal/config"
+	"github.com/Z3Labs/MockServer/internal/manager"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   string
+	}{
+		{
+			name:   "known vector",
+			secret: "key",
+			body:   "The quick brown fox jumps over the lazy dog",
+			want:   "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+		{
+			name:   "empty body",
+			secret: "key",
+			body:   "",
+			want:   "sha256=5d5d139563c95b5967b9bd9a8c9b233a9dedb45072794cd232dc1b74832607d0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign(%q, %q) = %s, want %s", tt.secret, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{name: "success", statuses: []int{200}, attempts: 1},
+		{name: "retried server error", statuses: []int{503, 200}, attempts: 2},
+		{name: "retried rate limit", statuses: []int{429, 500, 204}, attempts: 3},
+		{name: "client error is final", statuses: []int{400, 200}, attempts: 1},
+		{name: "gives up after max retries", statuses: []int{500, 500, 500, 500, 500, 500}, attempts: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []*http.Request
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				defer mu.Unlock()
+				requests = append(requests, r)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(tt.statuses[len(requests)-1])
+			}))
+			defer srv.Close()
+
+			target := &target{
+				conf:   config.WebhookConf{Url: srv.URL, Secret: "s3cret", MaxRetries: 3, RetryInterval: 1},
+				client: &http.Client{Timeout: time.Second},
+			}
+			event := manager.Event{Id: 7, Type: manager.EventScenarioStarted}
+			target.deliver(context.Background(), delivery{event: event, body: []byte(`{"id":7}`)})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(requests) != tt.attempts {
+				t.Fatalf("got %d attempts, want %d", len(requests), tt.attempts)
+			}
+			for i, r := range requests {
+				if r.Header.Get(HeaderEvent) != manager.EventScenarioStarted || r.Header.Get(HeaderDelivery) != "7" {
+					t.Errorf("attempt %d headers = %v", i, r.Header)
+				}
+				if got, want := r.Header.Get(HeaderSignature), Sign("s3cret", []byte(bodies[i])); got != want {
+					t.Errorf("attempt %d signature = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverStopsWithContext(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	target := &target{
+		conf:   config.WebhookConf{Url: srv.URL, MaxRetries: 10, RetryInterval: 1000},
+		client: &http.Client{Timeout: time.Second},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	target.deliver(ctx, delivery{event: manager.Event{Id: 1, Type: manager.EventScenarioCrashImminent}, body: []byte("{}")})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("deliver took %v after its context ended", elapsed)
+	}
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}